@@ -0,0 +1,185 @@
+//Package blocklist keeps songs the admin does not want to hear from being added to the queue
+package blocklist
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+/**
+	The blocklist contains youtube video IDs, youtube channels, regular expressions for song titles and file paths.
+	Every check returns an error explaining why the song was rejected, the message is shown to the user.
+	The blocklist is stored in a json file, which is written on every change.
+**/
+
+var (
+	blocklist    Blocklist
+	titleRegexes []*regexp.Regexp
+	listPath     string
+	mutex        sync.Mutex
+)
+
+//Blocklist contains everything which is blocked
+type Blocklist struct {
+	//VideoIDs are youtube video IDs
+	VideoIDs []string `json:"videoIDs"`
+	//Channels are youtube channel IDs or channel names
+	Channels []string `json:"channels"`
+	//Titles are regular expressions, songs whose title matches one of them are blocked, the case is ignored
+	Titles []string `json:"titles"`
+	//FilePaths are files or directories (ending with a path separator) of the offline song collection
+	FilePaths []string `json:"filePaths"`
+}
+
+//Init reads the blocklist from the given json file, when the file does not exist, nothing is blocked
+func Init(path string) error {
+	mutex.Lock()
+	listPath = path
+	blocklist = Blocklist{}
+	titleRegexes = nil
+	mutex.Unlock()
+
+	file, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("Init: %s", err)
+	}
+
+	var list Blocklist
+	err = json.Unmarshal(file, &list)
+	if err != nil {
+		return fmt.Errorf("Init: %s", err)
+	}
+
+	err = apply(list)
+	if err != nil {
+		return fmt.Errorf("Init: %s", err)
+	}
+	return nil
+}
+
+//Get returns the current blocklist
+func Get() Blocklist {
+	mutex.Lock()
+	defer mutex.Unlock()
+	return Blocklist{
+		VideoIDs:  append([]string{}, blocklist.VideoIDs...),
+		Channels:  append([]string{}, blocklist.Channels...),
+		Titles:    append([]string{}, blocklist.Titles...),
+		FilePaths: append([]string{}, blocklist.FilePaths...),
+	}
+}
+
+//Set replaces the blocklist and stores it, fails when one of the title regular expressions is invalid
+func Set(list Blocklist) error {
+	err := apply(list)
+	if err != nil {
+		return fmt.Errorf("Set: %s", err)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(listPath) == 0 {
+		return nil
+	}
+
+	file, err := json.MarshalIndent(blocklist, "", " ")
+	if err != nil {
+		return fmt.Errorf("Set: %s", err)
+	}
+
+	err = ioutil.WriteFile(listPath, file, 0644)
+	if err != nil {
+		return fmt.Errorf("Set: %s", err)
+	}
+	return nil
+}
+
+//apply cleans the entries of the list, compiles the title regular expressions and replaces the current blocklist
+func apply(list Blocklist) error {
+	list.VideoIDs = clean(list.VideoIDs)
+	list.Channels = clean(list.Channels)
+	list.Titles = clean(list.Titles)
+	list.FilePaths = clean(list.FilePaths)
+
+	regexes := make([]*regexp.Regexp, 0, len(list.Titles))
+	for _, title := range list.Titles {
+		regex, err := regexp.Compile("(?i)" + title)
+		if err != nil {
+			return fmt.Errorf("invalid title expression %s: %s", title, err)
+		}
+		regexes = append(regexes, regex)
+	}
+
+	mutex.Lock()
+	blocklist = list
+	titleRegexes = regexes
+	mutex.Unlock()
+	return nil
+}
+
+//clean removes surrounding spaces and empty entries
+func clean(entries []string) []string {
+	cleaned := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if len(entry) > 0 {
+			cleaned = append(cleaned, entry)
+		}
+	}
+	return cleaned
+}
+
+//CheckVideo returns an error when the youtube video with the given ID is blocked
+func CheckVideo(videoID string) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+	for _, blocked := range blocklist.VideoIDs {
+		if blocked == videoID {
+			return fmt.Errorf("This video is blocked by the admin!")
+		}
+	}
+	return nil
+}
+
+//CheckChannel returns an error when the youtube channel with the given ID or name is blocked
+func CheckChannel(channelID string, channelName string) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+	for _, blocked := range blocklist.Channels {
+		if (len(channelID) > 0 && blocked == channelID) || (len(channelName) > 0 && strings.EqualFold(blocked, channelName)) {
+			return fmt.Errorf("Songs of the channel %s are blocked by the admin!", channelName)
+		}
+	}
+	return nil
+}
+
+//CheckTitle returns an error when the title of a song contains a blocked word
+func CheckTitle(title string) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+	for _, regex := range titleRegexes {
+		if regex.MatchString(title) {
+			return fmt.Errorf("The song %s contains a word which is blocked by the admin!", title)
+		}
+	}
+	return nil
+}
+
+//CheckFile returns an error when the file or its directory is blocked
+func CheckFile(filePath string) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+	for _, blocked := range blocklist.FilePaths {
+		if blocked == filePath || (strings.HasSuffix(blocked, string(os.PathSeparator)) && strings.HasPrefix(filePath, blocked)) {
+			return fmt.Errorf("This song is blocked by the admin!")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package clients
+
+import (
+	"time"
+)
+
+//bans keeps the banned users by their ip together with the end of the ban, a zero time bans the user until the program restarts
+//kickHandlers remove the songs of a kicked user from the queues, they are registered by the packages which own the queues
+var (
+	bans         = make(map[string]time.Time)
+	kickHandlers []func(ip string) int
+)
+
+//OnKick registers a function which removes all songs of a kicked user and returns how many songs were removed
+func OnKick(handler func(ip string) int) {
+	mutex.Lock()
+	kickHandlers = append(kickHandlers, handler)
+	mutex.Unlock()
+}
+
+//Kick removes all pending downloads and queued songs of the user with the given ip
+//returns the count of removed songs
+func Kick(ip string) int {
+	mutex.Lock()
+	handlers := kickHandlers
+	mutex.Unlock()
+
+	//the handlers update the song counters of the user, so the mutex must not be locked while calling them
+	removed := 0
+	for _, handler := range handlers {
+		removed += handler(ip)
+	}
+	return removed
+}
+
+//Ban rejects all further requests of the user with the given ip for the given duration
+//a duration of 0 bans the user until the program restarts
+func Ban(ip string, duration time.Duration) {
+	until := time.Time{}
+	if duration > 0 {
+		until = time.Now().Add(duration)
+	}
+	mutex.Lock()
+	bans[ip] = until
+	mutex.Unlock()
+}
+
+//Unban lifts the ban of the user with the given ip, returns false when the user was not banned
+func Unban(ip string) bool {
+	mutex.Lock()
+	defer mutex.Unlock()
+	_, ok := bans[ip]
+	delete(bans, ip)
+	return ok
+}
+
+//IsBanned returns true and the end of the ban when the user with the given ip is banned
+//the end of the ban is zero for users who are banned until the program restarts
+func IsBanned(ip string) (bool, time.Time) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	until, ok := bans[ip]
+	if ok && until.IsZero() == false && time.Now().After(until) {
+		delete(bans, ip)
+		return false, time.Time{}
+	}
+	return ok, until
+}
+
+//GetBans returns all banned users by their ip together with the end of their ban, expired bans are removed
+func GetBans() map[string]time.Time {
+	mutex.Lock()
+	defer mutex.Unlock()
+	current := make(map[string]time.Time, len(bans))
+	for ip, until := range bans {
+		if until.IsZero() == false && time.Now().After(until) {
+			delete(bans, ip)
+			continue
+		}
+		current[ip] = until
+	}
+	return current
+}
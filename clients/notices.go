@@ -0,0 +1,22 @@
+package clients
+
+//notices are messages for users which are shown on their next visit of the website, f.e. when a download was rejected
+var (
+	notices = make(map[string][]string)
+)
+
+//AddNotice adds a message for the user with the given ip
+func AddNotice(ip string, message string) {
+	mutex.Lock()
+	notices[ip] = append(notices[ip], message)
+	mutex.Unlock()
+}
+
+//PopNotices returns all messages for the user with the given ip and removes them
+func PopNotices(ip string) []string {
+	mutex.Lock()
+	defer mutex.Unlock()
+	messages := notices[ip]
+	delete(notices, ip)
+	return messages
+}
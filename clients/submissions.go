@@ -0,0 +1,37 @@
+package clients
+
+import (
+	"time"
+)
+
+//submissions keeps the times at which every user submitted songs, used for limiting how many songs a user can add
+var (
+	submissions = make(map[string][]time.Time)
+)
+
+//AddSubmission records that the user with the given ip submitted a song just now
+func AddSubmission(ip string) {
+	mutex.Lock()
+	submissions[ip] = append(submissions[ip], time.Now())
+	mutex.Unlock()
+}
+
+//GetSubmissions returns how many songs the user submitted within the given time window and when the oldest of these songs was submitted
+//older submissions are forgotten
+func GetSubmissions(ip string, window time.Duration) (int, time.Time) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	times := submissions[ip]
+	start := 0
+	for start < len(times) && time.Since(times[start]) > window {
+		start++
+	}
+	times = times[start:]
+	submissions[ip] = times
+
+	if len(times) == 0 {
+		return 0, time.Time{}
+	}
+	return len(times), times[0]
+}
@@ -22,7 +22,11 @@ func GetUserNameToIP(ip string) string {
 		ip = split[0]
 	}
 	//get last number of IP which should be in a range of 0-254
-	lastNum, err := strconv.Atoi(strings.Split(ip, ".")[3])
+	parts := strings.Split(ip, ".")
+	if len(parts) != 4 {
+		return ip
+	}
+	lastNum, err := strconv.Atoi(parts[3])
 	if err != nil {
 		return ip
 	}
@@ -35,7 +39,7 @@ func GetUserNameToIP(ip string) string {
 //InitUserNames reads in the username file and stores them internally
 func InitUserNames(filename string) error {
 	//read in username file
-	fileContent, err := ioutil.ReadFile("usernames.txt")
+	fileContent, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return err
 	}
@@ -1,8 +1,15 @@
 package clients
 
 import (
-	"fmt"
 	"sync"
+
+	"github.com/procrastimax/goparty/logging"
+)
+
+const (
+	//HouseUserIP is the user of all songs which were not added by a guest, f.e. the songs queued by autoplay
+	//it is no real user, so it is not listed with the other users
+	HouseUserIP = "house"
 )
 
 //users is the map datastructure which contains all users (identified by their ip) and how many songs they currently added for downloading
@@ -84,6 +91,34 @@ func GetUserName(ip string) string {
 	return ""
 }
 
+//GetUsers returns the names of all known users by their IP, the house user is not included
+func GetUsers() map[string]string {
+	mutex.Lock()
+	defer mutex.Unlock()
+	names := make(map[string]string, len(users))
+	for ip, properties := range users {
+		if ip == HouseUserIP {
+			continue
+		}
+		names[ip] = properties.UserName
+	}
+	return names
+}
+
+//GetUserProperties returns a copy of the song counters of all known users by their IP
+func GetUserProperties() map[string]Properties {
+	mutex.Lock()
+	defer mutex.Unlock()
+	properties := make(map[string]Properties, len(users))
+	for ip, p := range users {
+		if ip == HouseUserIP {
+			continue
+		}
+		properties[ip] = *p
+	}
+	return properties
+}
+
 //Count returns the size of the user map, can be used to see how many users added a song for downloading
 func Count() int {
 	mutex.Lock()
@@ -94,6 +129,6 @@ func Count() int {
 //GetUserCounts returns the user added map
 func GetUserCounts() {
 	for k := range users {
-		fmt.Println(k, " - ", users[k].DownloadingSongs, " - ", users[k].PlaylistSongs)
+		logging.Debug("User counts", "ip", k, "downloading", users[k].DownloadingSongs, "playlist", users[k].PlaylistSongs)
 	}
 }
@@ -1,5 +1,12 @@
 module github.com/procrastimax/goparty
 
-go 1.14
+go 1.21
 
 require github.com/faiface/beep v1.0.2
+
+require (
+	github.com/hajimehoshi/go-mp3 v0.1.1 // indirect
+	github.com/hajimehoshi/oto v0.3.1 // indirect
+	github.com/pkg/errors v0.8.1 // indirect
+	golang.org/x/sys v0.0.0-20181228144115-9a3f9b0469bb // indirect
+)
@@ -0,0 +1,11 @@
+//Package html contains the templates of the website, they are embedded into the program so it can be started from any directory
+package html
+
+import (
+	"embed"
+)
+
+//Templates contains all html templates of the website
+//
+//go:embed *.html
+var Templates embed.FS
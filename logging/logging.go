@@ -0,0 +1,148 @@
+//Package logging is the leveled logger of goparty
+//the log is written as text or json to stderr or to a log file, so it is kept separate from the console on stdout
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+var (
+	level   = new(slog.LevelVar)
+	logger  = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+	logFile *rotatingFile
+	//loggerMutex guards the logger and the log file, which are replaced by Setup
+	loggerMutex sync.Mutex
+)
+
+//Settings specify where and how the log is written
+type Settings struct {
+	//Level is the lowest level which is logged: debug, info, warn or error
+	Level string
+	//Format is the format of the log lines: text or json
+	Format string
+	//File is the path of the log file, the log is written to stderr when it is empty
+	File string
+	//MaxSizeMB is the size of the log file in megabytes at which it is rotated, 0 disables the rotation
+	MaxSizeMB int
+	//MaxFiles is the count of rotated log files which are kept
+	MaxFiles int
+}
+
+//Setup sets up the logger with the given settings, the messages of the standard log package are logged as info
+func Setup(settings Settings) error {
+	err := SetLevel(settings.Level)
+	if err != nil {
+		return fmt.Errorf("Setup: %s", err)
+	}
+
+	var out io.Writer = os.Stderr
+	var file *rotatingFile
+	if len(settings.File) > 0 {
+		file, err = openRotatingFile(settings.File, int64(settings.MaxSizeMB)*1024*1024, settings.MaxFiles)
+		if err != nil {
+			return fmt.Errorf("Setup: %s", err)
+		}
+		out = file
+	}
+
+	var handler slog.Handler
+	switch strings.ToLower(settings.Format) {
+	case "", "text":
+		handler = slog.NewTextHandler(out, &slog.HandlerOptions{Level: level})
+	case "json":
+		handler = slog.NewJSONHandler(out, &slog.HandlerOptions{Level: level})
+	default:
+		if file != nil {
+			file.Close()
+		}
+		return fmt.Errorf("Setup: unknown log format %q, use text or json", settings.Format)
+	}
+
+	loggerMutex.Lock()
+	oldFile := logFile
+	logger = slog.New(handler)
+	logFile = file
+	slog.SetDefault(logger)
+	loggerMutex.Unlock()
+
+	if oldFile != nil {
+		oldFile.Close()
+	}
+	return nil
+}
+
+//ParseLevel returns the level with the given name: debug, info, warn or error
+func ParseLevel(name string) (slog.Level, error) {
+	var parsed slog.Level
+	if len(name) == 0 {
+		return slog.LevelInfo, nil
+	}
+	err := parsed.UnmarshalText([]byte(name))
+	if err != nil {
+		return 0, fmt.Errorf("unknown log level %q, use debug, info, warn or error", name)
+	}
+	return parsed, nil
+}
+
+//SetLevel sets the lowest level which is logged, the level can be changed while the program runs
+func SetLevel(name string) error {
+	parsed, err := ParseLevel(name)
+	if err != nil {
+		return err
+	}
+	level.Set(parsed)
+	return nil
+}
+
+//Close closes the log file, the log is written to stderr afterwards
+func Close() {
+	loggerMutex.Lock()
+	file := logFile
+	logFile = nil
+	logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+	slog.SetDefault(logger)
+	loggerMutex.Unlock()
+
+	if file != nil {
+		file.Close()
+	}
+}
+
+//get returns the current logger
+func get() *slog.Logger {
+	loggerMutex.Lock()
+	defer loggerMutex.Unlock()
+	return logger
+}
+
+//Debug logs details which are only needed when looking for a problem
+func Debug(msg string, args ...interface{}) {
+	get().Debug(msg, args...)
+}
+
+//Info logs what the program does, f.e. added songs or a started player
+func Info(msg string, args ...interface{}) {
+	get().Info(msg, args...)
+}
+
+//Warn logs problems the program can handle, f.e. a song which cannot be played
+func Warn(msg string, args ...interface{}) {
+	get().Warn(msg, args...)
+}
+
+//Error logs errors, f.e. when a file cannot be saved
+func Error(msg string, args ...interface{}) {
+	get().Error(msg, args...)
+}
+
+//Fatal logs the error and quits the program
+func Fatal(msg string, args ...interface{}) {
+	get().Error(msg, args...)
+	Close()
+	os.Exit(1)
+}
@@ -0,0 +1,108 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+/**
+	The log file is rotated when it reaches its maximum size:
+	goparty.log is renamed to goparty.log.1, goparty.log.1 to goparty.log.2 and so on, the oldest file is removed.
+**/
+
+//rotatingFile is a log file which is rotated when it gets too large
+type rotatingFile struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+	file     *os.File
+	size     int64
+	sync.Mutex
+}
+
+//openRotatingFile opens the log file at the given path, new lines are appended
+func openRotatingFile(path string, maxSize int64, maxFiles int) (*rotatingFile, error) {
+	r := &rotatingFile{path: path, maxSize: maxSize, maxFiles: maxFiles}
+	err := r.open()
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+//open opens the log file and reads its size
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	r.file = file
+	r.size = info.Size()
+	return nil
+}
+
+//rotate renames the log files and opens a new log file
+func (r *rotatingFile) rotate() error {
+	err := r.file.Close()
+	r.file = nil
+	if err != nil {
+		return err
+	}
+
+	if r.maxFiles < 1 {
+		err = os.Remove(r.path)
+	} else {
+		os.Remove(fmt.Sprintf("%s.%d", r.path, r.maxFiles))
+		for i := r.maxFiles - 1; i >= 1; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+		}
+		err = os.Rename(r.path, r.path+".1")
+	}
+	if err != nil && os.IsNotExist(err) == false {
+		return err
+	}
+	return r.open()
+}
+
+//Write writes a log line into the log file, the file is rotated first when the line does not fit anymore
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.file == nil {
+		return 0, fmt.Errorf("the log file is closed")
+	}
+
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		err := r.rotate()
+		if err != nil {
+			//the log line is written to stderr, so it does not get lost
+			fmt.Fprintf(os.Stderr, "could not rotate the log file %s: %s\n", r.path, err)
+			if r.file == nil {
+				return os.Stderr.Write(p)
+			}
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+//Close closes the log file
+func (r *rotatingFile) Close() error {
+	r.Lock()
+	defer r.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
@@ -1,6 +1,10 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"os"
+
 	"github.com/procrastimax/goparty/server"
 )
 
@@ -9,5 +13,22 @@ const (
 )
 
 func main() {
-	server.SetupServing()
+	options, err := server.ParseOptions(os.Args[1:])
+	if err == flag.ErrHelp {
+		return
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if options.PrintConfig {
+		err = server.PrintConfig(options)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	server.SetupServing(options)
 }
@@ -0,0 +1,237 @@
+package mp3
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/procrastimax/goparty/clients"
+	"github.com/procrastimax/goparty/logging"
+)
+
+/**
+	Autoplay keeps the party going when the music queue runs empty.
+	Songs are picked from the offline song DB, a directory, the play history or a m3u playlist
+	and are queued as the house user. Songs of the house user always yield to songs of the guests.
+	The next house song is queued as soon as the last song starts playing, so there is no gap between the songs.
+**/
+
+//AutoplayMode specifies where autoplay picks its songs from
+type AutoplayMode string
+
+const (
+	//AutoplayOff disables autoplay, the queue plays silence when it is empty
+	AutoplayOff AutoplayMode = "off"
+	//AutoplayRandom picks random songs from the offline song DB
+	AutoplayRandom AutoplayMode = "random"
+	//AutoplayDirectory picks random songs from the autoplay directory
+	AutoplayDirectory AutoplayMode = "directory"
+	//AutoplayHistory picks random songs from the play history
+	AutoplayHistory AutoplayMode = "history"
+	//AutoplayPlaylist plays the songs of the autoplay m3u playlist in order
+	AutoplayPlaylist AutoplayMode = "playlist"
+
+	//HouseUserIP is used as user for all songs queued by autoplay
+	HouseUserIP = clients.HouseUserIP
+
+	//autoplayRecentCount is the count of last played songs which are not picked again by the random modes
+	autoplayRecentCount = 20
+	//autoplayRetryDelay is the time to wait until autoplay tries again after it could not queue a song
+	autoplayRetryDelay = 10 * time.Second
+)
+
+var (
+	autoplayMode        = AutoplayOff
+	autoplayPath        string
+	autoplayPending     bool
+	autoplayFailed      time.Time
+	autoplayPlaylistIdx int
+	autoplayRand        = rand.New(rand.NewSource(time.Now().UnixNano()))
+	autoplayMutex       sync.Mutex
+)
+
+//SetAutoplay sets the autoplay mode, the path is the directory for AutoplayDirectory and the m3u file for AutoplayPlaylist
+//an empty mode disables autoplay
+func SetAutoplay(mode string, path string) error {
+	autoplay := AutoplayMode(strings.ToLower(mode))
+	switch autoplay {
+	case "":
+		autoplay = AutoplayOff
+	case AutoplayOff, AutoplayRandom, AutoplayHistory:
+	case AutoplayDirectory, AutoplayPlaylist:
+		if len(path) == 0 {
+			return fmt.Errorf("SetAutoplay: autoplay mode %s needs a path", autoplay)
+		}
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("SetAutoplay: %s", err)
+		}
+	default:
+		return fmt.Errorf("SetAutoplay: unknown autoplay mode %s", mode)
+	}
+
+	autoplayMutex.Lock()
+	autoplayMode = autoplay
+	autoplayPath = path
+	autoplayPlaylistIdx = 0
+	autoplayFailed = time.Time{}
+	autoplayMutex.Unlock()
+	return nil
+}
+
+//GetAutoplay returns the current autoplay mode and path
+func GetAutoplay() (AutoplayMode, string) {
+	autoplayMutex.Lock()
+	defer autoplayMutex.Unlock()
+	return autoplayMode, autoplayPath
+}
+
+//triggerAutoplay queues the next autoplay song in its own go routine
+//it is called by the music queue whenever no song follows the playing song, so the next song is already waiting and preloaded when the playing song ends
+//it is called from the audio path, so it must not block
+func triggerAutoplay() {
+	autoplayMutex.Lock()
+	defer autoplayMutex.Unlock()
+
+	if autoplayMode == AutoplayOff || autoplayPending || time.Since(autoplayFailed) < autoplayRetryDelay {
+		return
+	}
+	autoplayPending = true
+	go autoplayNext()
+}
+
+//autoplayNext picks the next autoplay song and adds it to the music queue as the house user
+func autoplayNext() {
+	filePath, err := pickAutoplaySong()
+	if err == nil {
+		dir, filename := filepath.Split(filePath)
+		err = AddMP3ToMusicQueue(dir, filename, HouseUserIP, false)
+	}
+
+	autoplayMutex.Lock()
+	autoplayPending = false
+	if err != nil {
+		autoplayFailed = time.Now()
+	}
+	autoplayMutex.Unlock()
+
+	if err != nil {
+		logging.Warn("Autoplay failed", "err", err)
+	}
+}
+
+//pickAutoplaySong returns the path of the next song which should be played by autoplay
+func pickAutoplaySong() (string, error) {
+	mode, path := GetAutoplay()
+
+	var candidates []string
+	var err error
+	switch mode {
+	case AutoplayRandom:
+		candidates = getSongDBFiles()
+	case AutoplayDirectory:
+		candidates, err = getMp3Files(path)
+	case AutoplayHistory:
+		candidates = getHistoryFiles()
+	case AutoplayPlaylist:
+		return nextPlaylistSong(path)
+	default:
+		return "", fmt.Errorf("autoplay is disabled")
+	}
+
+	if err != nil {
+		return "", err
+	}
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("no songs available for autoplay mode %s", mode)
+	}
+
+	//prefer songs which were not played recently, if all songs were played recently, just take any
+	recent := getRecentFiles(autoplayRecentCount)
+	fresh := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		if recent[candidate] == false {
+			fresh = append(fresh, candidate)
+		}
+	}
+	if len(fresh) > 0 {
+		candidates = fresh
+	}
+
+	autoplayMutex.Lock()
+	defer autoplayMutex.Unlock()
+	return candidates[autoplayRand.Intn(len(candidates))], nil
+}
+
+//nextPlaylistSong returns the next song of the autoplay playlist, the playlist starts again after the last song
+func nextPlaylistSong(playlist string) (string, error) {
+	entries, err := ReadM3U(playlist)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 0 {
+		return "", fmt.Errorf("autoplay playlist %s is empty", playlist)
+	}
+
+	autoplayMutex.Lock()
+	defer autoplayMutex.Unlock()
+	if autoplayPlaylistIdx >= len(entries) {
+		autoplayPlaylistIdx = 0
+	}
+	entry := entries[autoplayPlaylistIdx]
+	autoplayPlaylistIdx++
+	return entry, nil
+}
+
+//getMp3Files returns all mp3 files in the given directory and its subdirectories
+func getMp3Files(dir string) ([]string, error) {
+	files := make([]string, 0)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() == false && strings.HasSuffix(info.Name(), ".mp3") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("getMp3Files: %s", err)
+	}
+	return files, nil
+}
+
+//getHistoryFiles returns all distinct songs of the play history which still exist
+func getHistoryFiles() []string {
+	seen := make(map[string]bool)
+	files := make([]string, 0)
+	for _, entry := range GetHistory() {
+		if seen[entry.FilePath] {
+			continue
+		}
+		seen[entry.FilePath] = true
+		if checkMp3File(entry.FilePath) == nil {
+			files = append(files, entry.FilePath)
+		}
+	}
+	return files
+}
+
+//getRecentFiles returns the songs of the last count history entries and the songs in the queue
+func getRecentFiles(count int) map[string]bool {
+	recent := make(map[string]bool)
+	entries := GetHistory()
+	if len(entries) > count {
+		entries = entries[len(entries)-count:]
+	}
+	for _, entry := range entries {
+		recent[entry.FilePath] = true
+	}
+	for _, song := range queue.GetSongs() {
+		recent[song.FilePath] = true
+	}
+	return recent
+}
@@ -0,0 +1,115 @@
+package mp3
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sync"
+
+	"github.com/faiface/beep"
+)
+
+/**
+	Broadcasting of the played music to stream listeners, f.e. browsers in another room.
+	All samples the output plays are encoded as 16 bit stereo PCM and sent to every listener.
+	A listener which is too slow to receive the samples misses them instead of blocking the output.
+**/
+
+const (
+	//listenerBufferCount is the number of sample chunks buffered for every listener
+	listenerBufferCount = 32
+)
+
+var (
+	streamFormat   = beep.Format{SampleRate: SampleRate, NumChannels: 2, Precision: 2}
+	listeners      = make(map[*StreamListener]bool)
+	listenersMutex sync.Mutex
+	maxListeners   = 0
+)
+
+//StreamListener receives the played music as 16 bit little endian stereo PCM chunks
+type StreamListener struct {
+	C  <-chan []byte
+	ch chan []byte
+}
+
+//SetMaxStreamListeners sets the maximum number of listeners at the same time, 0 disables the streaming
+func SetMaxStreamListeners(count int) {
+	listenersMutex.Lock()
+	maxListeners = count
+	listenersMutex.Unlock()
+}
+
+//GetMaxStreamListeners returns the maximum number of listeners at the same time
+func GetMaxStreamListeners() int {
+	listenersMutex.Lock()
+	defer listenersMutex.Unlock()
+	return maxListeners
+}
+
+//GetStreamListenerCount returns the number of current listeners
+func GetStreamListenerCount() int {
+	listenersMutex.Lock()
+	defer listenersMutex.Unlock()
+	return len(listeners)
+}
+
+//AddStreamListener registers a new listener which receives all played samples
+//returns an error when the maximum number of listeners is reached
+func AddStreamListener() (*StreamListener, error) {
+	listenersMutex.Lock()
+	defer listenersMutex.Unlock()
+
+	if maxListeners <= 0 {
+		return nil, fmt.Errorf("streaming is disabled")
+	}
+	if len(listeners) >= maxListeners {
+		return nil, fmt.Errorf("the maximum of %d listeners is reached", maxListeners)
+	}
+
+	ch := make(chan []byte, listenerBufferCount)
+	listener := &StreamListener{C: ch, ch: ch}
+	listeners[listener] = true
+	return listener, nil
+}
+
+//RemoveStreamListener removes the listener, its channel is closed afterwards
+func RemoveStreamListener(listener *StreamListener) {
+	listenersMutex.Lock()
+	defer listenersMutex.Unlock()
+
+	if listeners[listener] {
+		delete(listeners, listener)
+		close(listener.ch)
+	}
+}
+
+//WriteStreamHeader writes a wav header for the PCM stream of a listener
+//the stream has no known length, so the maximum size is used
+func WriteStreamHeader(w io.Writer) error {
+	return writeWAVHeader(w, streamFormat, math.MaxUint32-wavHeaderSize)
+}
+
+//broadcast encodes the samples and sends them to all listeners
+func broadcast(samples [][2]float64) {
+	listenersMutex.Lock()
+	defer listenersMutex.Unlock()
+
+	if len(listeners) == 0 || len(samples) == 0 {
+		return
+	}
+
+	chunk := make([]byte, len(samples)*streamFormat.Width())
+	n := 0
+	for _, sample := range samples {
+		n += streamFormat.EncodeSigned(chunk[n:], sample)
+	}
+
+	for listener := range listeners {
+		select {
+		case listener.ch <- chunk:
+		default:
+			//the listener is too slow, it misses this chunk
+		}
+	}
+}
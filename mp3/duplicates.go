@@ -0,0 +1,130 @@
+package mp3
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+/**
+	Duplicate detection keeps the same song from being in the queue more than once.
+	A song is the same when it has the same file path or was downloaded from the same youtube video,
+	optionally songs with the same normalized title are also treated as the same song.
+	Submitting a song which is already in the queue upvotes the queued song instead,
+	songs which were played within the cooldown window cannot be submitted again.
+**/
+
+var (
+	duplicateCooldown time.Duration
+	duplicateTitles   = false
+	//duplicateMutex guards the settings, which are changed by a config reload while songs are submitted
+	duplicateMutex sync.Mutex
+	//titleNoiseRegex matches words in song titles which do not change the song, f.e. "official video"
+	titleNoiseRegex = regexp.MustCompile("(?i)\\b(official|music|lyrics?|video|audio|hd|hq)\\b")
+	//titleSymbolRegex matches everything which is not a letter or a number
+	titleSymbolRegex = regexp.MustCompile("[^\\p{L}\\p{N}]+")
+)
+
+//SongKey identifies a song for the duplicate detection, empty fields are not compared
+type SongKey struct {
+	FilePath string
+	//SongName is compared by its normalized title when title matching is enabled
+	SongName string
+	VideoID  string
+}
+
+//SetDuplicateDetection sets the cooldown window in which played songs cannot be submitted again
+//when matchTitles is set, songs with the same normalized title are also treated as duplicates
+func SetDuplicateDetection(cooldown time.Duration, matchTitles bool) {
+	duplicateMutex.Lock()
+	duplicateCooldown = cooldown
+	duplicateTitles = matchTitles
+	duplicateMutex.Unlock()
+}
+
+//getDuplicateDetection returns the cooldown window and whether songs with the same title are duplicates
+func getDuplicateDetection() (time.Duration, bool) {
+	duplicateMutex.Lock()
+	defer duplicateMutex.Unlock()
+	return duplicateCooldown, duplicateTitles
+}
+
+//NormalizeTitle returns a simplified version of a song title for comparing titles
+//everything in brackets, words like "official video", symbols and the case are removed
+func NormalizeTitle(title string) string {
+	title = ParenthesisRegex.ReplaceAllString(title, " ")
+	title = titleNoiseRegex.ReplaceAllString(title, " ")
+	title = titleSymbolRegex.ReplaceAllString(strings.ToLower(title), " ")
+	return strings.Join(strings.Fields(title), " ")
+}
+
+//matches returns true when the song with the given file path and name is the song of the key
+//when matchTitles is set, songs with the same normalized title match too
+func (k SongKey) matches(filePath string, songName string, matchTitles bool) bool {
+	if len(k.FilePath) > 0 && k.FilePath == filePath {
+		return true
+	}
+
+	if len(k.VideoID) > 0 && strings.HasSuffix(filePath, "#____#"+k.VideoID+".mp3") {
+		return true
+	}
+
+	if matchTitles {
+		name := k.SongName
+		if len(name) == 0 && len(k.FilePath) > 0 {
+			name = getSongName(filepath.Base(k.FilePath))
+		}
+		normalized := NormalizeTitle(name)
+		if len(normalized) > 0 && normalized == NormalizeTitle(songName) {
+			return true
+		}
+	}
+	return false
+}
+
+//UpvoteDuplicate upvotes the song of the key for the user when the song is already in the queue
+//returns the queued song and its position in the queue, returns false when the song is not in the queue
+func UpvoteDuplicate(key SongKey, userIP string) (Song, int, bool) {
+	_, matchTitles := getDuplicateDetection()
+
+	queue.Lock()
+	defer queue.Unlock()
+
+	for i := range queue.songs {
+		if key.matches(queue.songs[i].FilePath, queue.songs[i].SongName, matchTitles) {
+			id := queue.songs[i].id
+			queue.upvoteSong(i, userIP)
+			//upvoting can move the song forward in the queue
+			idx := queue.indexOf(id)
+			return queue.songs[idx].Song, idx, true
+		}
+	}
+	return Song{}, 0, false
+}
+
+//GetRecentlyPlayed returns the last history entry of the song of the key when it was played within the cooldown window
+func GetRecentlyPlayed(key SongKey) (HistoryEntry, bool) {
+	cooldown, matchTitles := getDuplicateDetection()
+	if cooldown <= 0 {
+		return HistoryEntry{}, false
+	}
+
+	entries := GetHistory()
+	for i := len(entries) - 1; i >= 0; i-- {
+		if time.Since(entries[i].End) > cooldown {
+			break
+		}
+		if key.matches(entries[i].FilePath, entries[i].SongName, matchTitles) {
+			return entries[i], true
+		}
+	}
+	return HistoryEntry{}, false
+}
+
+//GetDuplicateCooldown returns the cooldown window in which played songs cannot be submitted again
+func GetDuplicateCooldown() time.Duration {
+	cooldown, _ := getDuplicateDetection()
+	return cooldown
+}
@@ -0,0 +1,224 @@
+package mp3
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/procrastimax/goparty/logging"
+)
+
+/**
+	The play history keeps track of every song which was played (or skipped).
+	Every entry is appended as a single json line to the history file, so the history survives restarts.
+	Songs finish in the audio path, so the history file is written by its own go routine.
+**/
+
+const (
+	//historyWriteBuffer is the count of history entries which can wait for being written without blocking
+	historyWriteBuffer = 64
+)
+
+var (
+	history      []HistoryEntry
+	historyMutex sync.Mutex
+	historyPath  string
+	//historyWrites are the entries which still need to be appended to the history file
+	historyWrites      = make(chan historyWrite, historyWriteBuffer)
+	historyWriterStart sync.Once
+	//historyPending counts the entries which were not written to the history file yet
+	historyPending sync.WaitGroup
+)
+
+//historyWrite is a history entry which needs to be appended to the history file at the given path
+type historyWrite struct {
+	path  string
+	entry HistoryEntry
+}
+
+//HistoryEntry is a single song which was played
+type HistoryEntry struct {
+	SongName string    `json:"song"`
+	FilePath string    `json:"file"`
+	UserIP   string    `json:"userIP"`
+	UserName string    `json:"userName"`
+	Upvotes  int       `json:"upvotes"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Skipped  bool      `json:"skipped"`
+}
+
+//Duration returns how long the song was played
+func (e HistoryEntry) Duration() time.Duration {
+	return e.End.Sub(e.Start)
+}
+
+//InitHistory reads the play history from the given file, new entries are appended to this file
+func InitHistory(path string) error {
+	waitForHistory()
+
+	historyMutex.Lock()
+	defer historyMutex.Unlock()
+
+	historyPath = path
+	history = make([]HistoryEntry, 0)
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("InitHistory: %s", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry HistoryEntry
+		err = json.Unmarshal(scanner.Bytes(), &entry)
+		if err != nil {
+			return fmt.Errorf("InitHistory: %s", err)
+		}
+		history = append(history, entry)
+	}
+
+	if scanner.Err() != nil {
+		return fmt.Errorf("InitHistory: %s", scanner.Err())
+	}
+	return nil
+}
+
+//GetHistory returns all played songs, the oldest song comes first
+func GetHistory() []HistoryEntry {
+	historyMutex.Lock()
+	defer historyMutex.Unlock()
+	entries := make([]HistoryEntry, len(history))
+	copy(entries, history)
+	return entries
+}
+
+//addHistoryEntry adds the song to the history, the entry is appended to the history file by the history writer
+//this is called in the audio path, so it never writes the file itself
+func addHistoryEntry(entry HistoryEntry) {
+	historyMutex.Lock()
+	history = append(history, entry)
+	path := historyPath
+	//the write is counted before the entry can be seen, so waitForHistory never misses it
+	if len(path) > 0 {
+		historyPending.Add(1)
+	}
+	historyMutex.Unlock()
+
+	if len(path) == 0 {
+		return
+	}
+
+	historyWriterStart.Do(func() { go writeHistory() })
+	select {
+	case historyWrites <- historyWrite{path: path, entry: entry}:
+	default:
+		//the writer is behind, the audio path must not wait for it
+		go func() { historyWrites <- historyWrite{path: path, entry: entry} }()
+	}
+}
+
+//writeHistory appends the added history entries to the history file, it runs in its own go routine
+func writeHistory() {
+	for write := range historyWrites {
+		err := appendHistoryFile(write.path, write.entry)
+		if err != nil {
+			logging.Error("Could not add the song to the play history", "err", err)
+		}
+		historyPending.Done()
+	}
+}
+
+//waitForHistory waits until all added history entries are written to the history file
+func waitForHistory() {
+	historyPending.Wait()
+}
+
+//appendHistoryFile appends the entry as a single json line to the history file
+func appendHistoryFile(path string, entry HistoryEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("appendHistoryFile: %s", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("appendHistoryFile: %s", err)
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(line, '\n'))
+	if err != nil {
+		return fmt.Errorf("appendHistoryFile: %s", err)
+	}
+	return nil
+}
+
+//WriteHistoryJSON writes the given history entries as json array
+func WriteHistoryJSON(w io.Writer, entries []HistoryEntry) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", " ")
+	return encoder.Encode(entries)
+}
+
+//WriteHistoryCSV writes the given history entries as csv with a header line
+func WriteHistoryCSV(w io.Writer, entries []HistoryEntry) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write([]string{"song", "file", "userIP", "userName", "upvotes", "start", "end", "skipped"})
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		err = writer.Write([]string{
+			entry.SongName,
+			entry.FilePath,
+			entry.UserIP,
+			entry.UserName,
+			strconv.Itoa(entry.Upvotes),
+			entry.Start.Format(time.RFC3339),
+			entry.End.Format(time.RFC3339),
+			strconv.FormatBool(entry.Skipped),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+//WriteHistoryM3U writes the given history entries as extended m3u playlist
+func WriteHistoryM3U(w io.Writer, entries []HistoryEntry) error {
+	_, err := fmt.Fprintln(w, "#EXTM3U")
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		//the length of skipped songs is unknown
+		length := -1
+		if entry.Skipped == false {
+			length = int(entry.Duration().Seconds())
+		}
+
+		_, err = fmt.Fprintf(w, "#EXTINF:%d,%s\n%s\n", length, entry.SongName, entry.FilePath)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
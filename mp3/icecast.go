@@ -0,0 +1,287 @@
+package mp3
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/faiface/beep"
+
+	"github.com/procrastimax/goparty/logging"
+)
+
+/**
+	Icecast source client output, the played music is encoded to mp3 by ffmpeg and sent to an icecast mount.
+	The title of the currently playing song is sent to the icecast server whenever the queue advances.
+	When the connection is lost, the output reconnects with an increasing delay, the queue does not advance meanwhile.
+**/
+
+const (
+	//icecastTimeout is the timeout for connecting to the icecast server and updating the metadata
+	icecastTimeout = 5 * time.Second
+	//defaultIcecastBitrate is the mp3 bitrate in kbit/s used when no bitrate is configured
+	defaultIcecastBitrate = 192
+	//icecastReconnectDelay is the delay before the first reconnect when the connection was lost, it doubles with every failed attempt
+	icecastReconnectDelay = time.Second
+	//icecastReconnectAttempts is the count of reconnects before the output gives up and the player is stopped
+	icecastReconnectAttempts = 5
+)
+
+//IcecastConfig are the settings for sending the music to an icecast mount
+type IcecastConfig struct {
+	Host     string
+	Port     int
+	Mount    string
+	Password string
+	//Bitrate is the mp3 bitrate in kbit/s
+	Bitrate int
+}
+
+//address returns the host:port of the icecast server
+func (c IcecastConfig) address() string {
+	return net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+}
+
+//mount returns the mount point with a leading slash
+func (c IcecastConfig) mount() string {
+	if strings.HasPrefix(c.Mount, "/") {
+		return c.Mount
+	}
+	return "/" + c.Mount
+}
+
+//authorization returns the basic auth header value for the source user
+func (c IcecastConfig) authorization() string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte("source:"+c.Password))
+}
+
+//icecastOutput sends the music to an icecast server
+type icecastOutput struct {
+	clockedOutput
+	config    IcecastConfig
+	conn      net.Conn
+	encoder   *exec.Cmd
+	encoderIn io.WriteCloser
+	songID    int
+}
+
+//NewIcecastOutput returns an output which sends the music as mp3 stream to the given icecast mount
+//ffmpeg is needed for encoding the mp3 stream
+func NewIcecastOutput(config IcecastConfig) Output {
+	if config.Bitrate <= 0 {
+		config.Bitrate = defaultIcecastBitrate
+	}
+	return &icecastOutput{config: config}
+}
+
+func (i *icecastOutput) Start(sampleRate beep.SampleRate, streamer beep.Streamer) error {
+	err := i.open(sampleRate)
+	if err != nil {
+		return fmt.Errorf("icecast output: %s", err)
+	}
+
+	format := beep.Format{SampleRate: sampleRate, NumChannels: 2, Precision: 2}
+	buf := make([]byte, sampleRate.N(outputBufferDuration)*format.Width())
+	i.start(sampleRate, streamer, func(samples [][2]float64) error {
+		i.checkSongChange()
+
+		n := 0
+		for _, sample := range samples {
+			n += format.EncodeSigned(buf[n:], sample)
+		}
+		_, err := i.encoderIn.Write(buf[:n])
+		if err != nil {
+			logging.Warn("Lost the connection to the icecast server", "err", err)
+			return i.reconnect(sampleRate)
+		}
+		return nil
+	})
+
+	logging.Info("Sending music to icecast", "address", i.config.address(), "mount", i.config.mount())
+	return nil
+}
+
+func (i *icecastOutput) Stop() error {
+	i.stop()
+	err := i.close()
+	if err != nil {
+		return fmt.Errorf("icecast output: %s", err)
+	}
+	return nil
+}
+
+//open connects to the icecast server and starts the ffmpeg encoder which sends the mp3 stream to the connection
+func (i *icecastOutput) open(sampleRate beep.SampleRate) error {
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return fmt.Errorf("ffmpeg is needed for encoding the stream: %s", err)
+	}
+
+	conn, err := i.connect()
+	if err != nil {
+		return err
+	}
+
+	encoder := exec.Command(ffmpeg, "-loglevel", "error",
+		"-f", "s16le", "-ar", strconv.Itoa(int(sampleRate)), "-ac", "2", "-i", "pipe:0",
+		"-f", "mp3", "-b:a", strconv.Itoa(i.config.Bitrate)+"k", "pipe:1")
+	encoder.Stdout = conn
+	encoderIn, err := encoder.StdinPipe()
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	err = encoder.Start()
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	i.conn = conn
+	i.encoder = encoder
+	i.encoderIn = encoderIn
+	//no song has an id below 0, so the title is sent with the first samples
+	i.songID = -2
+	return nil
+}
+
+//close stops the encoder and closes the connection to the icecast server
+func (i *icecastOutput) close() error {
+	if i.encoder == nil {
+		return nil
+	}
+
+	//closing the input lets ffmpeg flush the last frames and exit
+	i.encoderIn.Close()
+	err := i.encoder.Wait()
+	i.conn.Close()
+	i.encoder = nil
+	return err
+}
+
+//reconnect opens a new connection after the connection was lost, the delay between the attempts doubles every time
+//returns an error when all attempts failed, then the output stops, returns nil without a connection when the output is stopped meanwhile
+func (i *icecastOutput) reconnect(sampleRate beep.SampleRate) error {
+	//the encoder cannot write to the lost connection anymore, closing the connection first lets it exit immediately
+	i.conn.Close()
+	i.close()
+
+	delay := icecastReconnectDelay
+	var err error
+	for attempt := 1; attempt <= icecastReconnectAttempts; attempt++ {
+		select {
+		case <-i.quit:
+			return nil
+		case <-time.After(delay):
+		}
+
+		err = i.open(sampleRate)
+		if err == nil {
+			logging.Info("Reconnected to the icecast server", "address", i.config.address(), "attempt", attempt)
+			return nil
+		}
+		logging.Warn("Could not reconnect to the icecast server", "attempt", attempt, "err", err)
+		delay *= 2
+	}
+	return fmt.Errorf("icecast output: connection lost: %s", err)
+}
+
+//connect opens the source connection to the icecast mount
+func (i *icecastOutput) connect() (net.Conn, error) {
+	conn, err := net.DialTimeout("tcp", i.config.address(), icecastTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	request := strings.Builder{}
+	request.WriteString("PUT " + i.config.mount() + " HTTP/1.1\r\n")
+	request.WriteString("Host: " + i.config.address() + "\r\n")
+	request.WriteString("Authorization: " + i.config.authorization() + "\r\n")
+	request.WriteString("User-Agent: goparty\r\n")
+	request.WriteString("Content-Type: audio/mpeg\r\n")
+	request.WriteString("Ice-Name: GoParty\r\n")
+	request.WriteString("Ice-Public: 0\r\n")
+	request.WriteString("Ice-Audio-Info: bitrate=" + strconv.Itoa(i.config.Bitrate) + "\r\n")
+	request.WriteString("Expect: 100-continue\r\n\r\n")
+
+	conn.SetDeadline(time.Now().Add(icecastTimeout))
+	_, err = conn.Write([]byte(request.String()))
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	response, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("invalid response from icecast server: %s", err)
+	}
+	response.Body.Close()
+
+	if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusContinue {
+		conn.Close()
+		return nil, fmt.Errorf("icecast server refused the source connection: %s", response.Status)
+	}
+
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+//checkSongChange sends the title of the current song to the icecast server when the queue advanced
+func (i *icecastOutput) checkSongChange() {
+	song, id, ok := queue.Current()
+	if ok == false {
+		//the queue is empty
+		id = -1
+	}
+	if id == i.songID {
+		return
+	}
+	i.songID = id
+
+	title := "GoParty"
+	if ok {
+		title = song.SongName
+		if len(song.UserName) > 0 {
+			title += " (added by " + song.UserName + ")"
+		}
+	}
+	go i.updateMetadata(title)
+}
+
+//updateMetadata sets the title of the currently playing song at the icecast mount
+func (i *icecastOutput) updateMetadata(title string) {
+	query := url.Values{}
+	query.Set("mount", i.config.mount())
+	query.Set("mode", "updinfo")
+	query.Set("song", title)
+
+	request, err := http.NewRequest("GET", "http://"+i.config.address()+"/admin/metadata?"+query.Encode(), nil)
+	if err != nil {
+		logging.Warn("Could not update the icecast metadata", "err", err)
+		return
+	}
+	request.Header.Set("Authorization", i.config.authorization())
+	request.Header.Set("User-Agent", "goparty")
+
+	client := http.Client{Timeout: icecastTimeout}
+	response, err := client.Do(request)
+	if err != nil {
+		logging.Warn("Could not update the icecast metadata", "err", err)
+		return
+	}
+	response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		logging.Warn("Could not update the icecast metadata", "status", response.Status)
+	}
+}
@@ -0,0 +1,373 @@
+package mp3
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/effects"
+
+	"github.com/procrastimax/goparty/logging"
+)
+
+/**
+	Loudness normalization of songs, the loudness is estimated in a ReplayGain-like way:
+	the song is split into blocks of 50ms, the RMS of every block is calculated and the 95th percentile
+	of all block values is taken as the loudness of the song.
+	The loudness and the peak of a song is stored in a json file, so every song only needs to be analyzed once.
+**/
+
+const (
+	//TargetLoudness is the loudness in dBFS all songs are normalized to
+	TargetLoudness = -18.0
+	//maxGain is the maximum gain in dB applied to quiet songs
+	maxGain = 12.0
+	//loudnessBlockDuration is the length of a single block used for calculating the RMS
+	loudnessBlockDuration = 50 * time.Millisecond
+	//loudnessPercentile is the percentile of the sorted block RMS values used as the song loudness
+	loudnessPercentile = 0.95
+	//loudnessSaveInterval is the count of analyzed songs after which the loudness db is written when analyzing the whole song db
+	loudnessSaveInterval = 50
+)
+
+var (
+	loudnessDB     map[string]*loudnessInfo
+	loudnessMutex  sync.Mutex
+	loudnessDBPath string
+	//loudnessPending contains the songs which are analyzed right now, the channel is closed when the analysis is done
+	loudnessPending   = make(map[string]chan struct{})
+	normalizeLoudness = false
+	//loudnessAnalyses counts the running analyses, so they are finished before the loudness db is read again or the program quits
+	loudnessAnalyses sync.WaitGroup
+	//loudnessStopped is set by StopLoudnessAnalysis, no new analyses are started afterwards until the loudness db is read again
+	loudnessStopped = false
+)
+
+//loudnessInfo is the analyzed loudness of a single song
+type loudnessInfo struct {
+	//Loudness is the estimated loudness of the song in dBFS
+	Loudness float64 `json:"loudness"`
+	//Peak is the maximum absolute sample value of the song
+	Peak float64 `json:"peak"`
+}
+
+//gain returns the gain in dB needed to reach the TargetLoudness, the gain is limited so that the song does not clip
+func (l *loudnessInfo) gain() float64 {
+	gain := TargetLoudness - l.Loudness
+	if gain > maxGain {
+		gain = maxGain
+	}
+	if l.Peak > 0 {
+		peakGain := -20 * math.Log10(l.Peak)
+		if gain > peakGain {
+			gain = peakGain
+		}
+	}
+	return gain
+}
+
+//SetLoudnessNormalization enables or disables the loudness normalization of queued songs
+func SetLoudnessNormalization(enabled bool) {
+	loudnessMutex.Lock()
+	normalizeLoudness = enabled
+	loudnessMutex.Unlock()
+}
+
+//isLoudnessNormalized returns true when the loudness of queued songs gets normalized
+func isLoudnessNormalized() bool {
+	loudnessMutex.Lock()
+	defer loudnessMutex.Unlock()
+	return normalizeLoudness
+}
+
+//InitLoudnessDB reads the stored loudness values from the given json file, the file is created when it does not exist
+//running analyses are finished and stored in the old file before the new file is read
+func InitLoudnessDB(dbPath string) error {
+	StopLoudnessAnalysis()
+
+	loudnessMutex.Lock()
+	defer loudnessMutex.Unlock()
+
+	loudnessStopped = false
+	loudnessDBPath = dbPath
+	loudnessDB = make(map[string]*loudnessInfo)
+
+	file, err := ioutil.ReadFile(dbPath)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("InitLoudnessDB: %s", err)
+	}
+
+	err = json.Unmarshal(file, &loudnessDB)
+	if err != nil {
+		return fmt.Errorf("InitLoudnessDB: %s", err)
+	}
+	return nil
+}
+
+//saveLoudnessDB writes the loudness db to the json file, the loudnessMutex must be held by the caller
+func saveLoudnessDB() error {
+	if len(loudnessDBPath) == 0 {
+		return nil
+	}
+
+	file, err := json.MarshalIndent(loudnessDB, "", " ")
+	if err != nil {
+		return fmt.Errorf("saveLoudnessDB: %s", err)
+	}
+
+	err = ioutil.WriteFile(loudnessDBPath, file, 0644)
+	if err != nil {
+		return fmt.Errorf("saveLoudnessDB: %s", err)
+	}
+	return nil
+}
+
+//AnalyzeLoudness decodes the given mp3 file and returns its estimated loudness in dBFS and its peak sample value
+func AnalyzeLoudness(filename string) (float64, float64, error) {
+	streamer, format, err := loadMp3File(filename)
+	if err != nil {
+		return 0, 0, fmt.Errorf("AnalyzeLoudness: %s", err)
+	}
+	defer (*streamer).Close()
+
+	blockSize := format.SampleRate.N(loudnessBlockDuration)
+	samples := make([][2]float64, blockSize)
+	blocks := make([]float64, 0)
+	peak := 0.0
+
+	for {
+		n, ok := (*streamer).Stream(samples)
+		if n > 0 {
+			sum := 0.0
+			for _, sample := range samples[:n] {
+				sum += (sample[0]*sample[0] + sample[1]*sample[1]) / 2
+				peak = math.Max(peak, math.Max(math.Abs(sample[0]), math.Abs(sample[1])))
+			}
+			blocks = append(blocks, sum/float64(n))
+		}
+		if !ok {
+			break
+		}
+	}
+
+	if err := (*streamer).Err(); err != nil {
+		return 0, 0, fmt.Errorf("AnalyzeLoudness: %s", err)
+	}
+
+	if len(blocks) == 0 {
+		return 0, 0, fmt.Errorf("AnalyzeLoudness: %s contains no samples", filename)
+	}
+
+	sort.Float64s(blocks)
+	meanSquare := blocks[int(float64(len(blocks)-1)*loudnessPercentile)]
+
+	//a completely silent song would result in -Inf
+	if meanSquare <= 0 {
+		return TargetLoudness, peak, nil
+	}
+	return 10 * math.Log10(meanSquare), peak, nil
+}
+
+//getLoudness returns the stored loudness info of a song, when the song was not analyzed before, it gets analyzed and stored
+//the song is decoded completely for the analysis, so this must never be called from the http handlers or the audio path
+func getLoudness(filename string) (*loudnessInfo, error) {
+	info, analyzed, err := analyzeSong(filename)
+	if err != nil || analyzed == false {
+		return info, err
+	}
+
+	loudnessMutex.Lock()
+	defer loudnessMutex.Unlock()
+	return info, saveLoudnessDB()
+}
+
+//analyzeSong returns the loudness info of a song and analyzes it when it is not stored yet, the loudness db is not written
+//returns true when the song got analyzed by this call, concurrent calls for the same song wait for the running analysis
+func analyzeSong(filename string) (*loudnessInfo, bool, error) {
+	loudnessMutex.Lock()
+	for {
+		if info, ok := loudnessDB[filename]; ok {
+			loudnessMutex.Unlock()
+			return info, false, nil
+		}
+		pending, ok := loudnessPending[filename]
+		if ok == false {
+			break
+		}
+		loudnessMutex.Unlock()
+		<-pending
+		loudnessMutex.Lock()
+		if _, ok := loudnessDB[filename]; ok == false {
+			//the other analysis failed, there is no need to try it again
+			loudnessMutex.Unlock()
+			return nil, false, fmt.Errorf("analyzeSong: could not analyze %s", filename)
+		}
+	}
+	pending := make(chan struct{})
+	loudnessPending[filename] = pending
+	loudnessMutex.Unlock()
+
+	loudness, peak, err := AnalyzeLoudness(filename)
+
+	loudnessMutex.Lock()
+	defer loudnessMutex.Unlock()
+	delete(loudnessPending, filename)
+	close(pending)
+	if err != nil {
+		return nil, false, err
+	}
+	info := &loudnessInfo{Loudness: loudness, Peak: peak}
+	if loudnessDB != nil {
+		loudnessDB[filename] = info
+	}
+	return info, true, nil
+}
+
+//startLoudnessAnalysis adds an analysis to the running analyses, the caller must call loudnessAnalyses.Done when it is finished
+//returns false when the analysis was stopped and the song must not be analyzed
+func startLoudnessAnalysis() bool {
+	loudnessMutex.Lock()
+	defer loudnessMutex.Unlock()
+	if loudnessStopped {
+		return false
+	}
+	loudnessAnalyses.Add(1)
+	return true
+}
+
+//isLoudnessAnalysisStopped returns true when StopLoudnessAnalysis was called
+func isLoudnessAnalysisStopped() bool {
+	loudnessMutex.Lock()
+	defer loudnessMutex.Unlock()
+	return loudnessStopped
+}
+
+//StopLoudnessAnalysis stops the analysis of the song db and waits until all running analyses are finished and stored
+//no new analyses are started until the loudness db is read again with InitLoudnessDB
+func StopLoudnessAnalysis() {
+	loudnessMutex.Lock()
+	loudnessStopped = true
+	loudnessMutex.Unlock()
+	loudnessAnalyses.Wait()
+}
+
+//analyzeSongNow analyzes the song and stores its loudness when the loudness normalization is enabled, it returns when the song is analyzed
+func analyzeSongNow(filename string) {
+	if isLoudnessNormalized() == false || startLoudnessAnalysis() == false {
+		return
+	}
+	defer loudnessAnalyses.Done()
+
+	_, err := getLoudness(filename)
+	if err != nil {
+		logging.Warn("Could not analyze the song", "file", filename, "err", err)
+	}
+}
+
+//analyzeInBackground analyzes the song in its own go routine when the loudness normalization is enabled
+func analyzeInBackground(filename string) {
+	if isLoudnessNormalized() == false || startLoudnessAnalysis() == false {
+		return
+	}
+	go func() {
+		defer loudnessAnalyses.Done()
+		_, err := getLoudness(filename)
+		if err != nil {
+			logging.Warn("Could not analyze the song", "file", filename, "err", err)
+		}
+	}()
+}
+
+//analyzeSongDBInBackground analyzes all songs of the songDB in its own go routine when the loudness normalization is enabled
+func analyzeSongDBInBackground() {
+	if isLoudnessNormalized() == false || startLoudnessAnalysis() == false {
+		return
+	}
+	go func() {
+		defer loudnessAnalyses.Done()
+		analyzeSongDB()
+	}()
+}
+
+//GetSongGain returns the gain in dB which is applied to the given song when normalizing the loudness
+//the song gets analyzed when it was not analyzed before
+func GetSongGain(filename string) (float64, error) {
+	info, err := getLoudness(filename)
+	if err != nil {
+		return 0, err
+	}
+	return info.gain(), nil
+}
+
+//cachedSongGain returns the gain in dB of an already analyzed song, returns false when the song was not analyzed yet
+func cachedSongGain(filename string) (float64, bool) {
+	loudnessMutex.Lock()
+	defer loudnessMutex.Unlock()
+	info, ok := loudnessDB[filename]
+	if ok == false {
+		return 0, false
+	}
+	return info.gain(), true
+}
+
+//applyLoudnessNormalization wraps the streamer with the needed gain to normalize the loudness of the song
+//only already analyzed songs are normalized, songs without a stored loudness are played with unity gain and analyzed in the background
+func applyLoudnessNormalization(filename string, streamer beep.Streamer) beep.Streamer {
+	if isLoudnessNormalized() == false {
+		return streamer
+	}
+
+	gain, ok := cachedSongGain(filename)
+	if ok == false {
+		analyzeInBackground(filename)
+		return streamer
+	}
+
+	//a volume of dB/20 with base 10 changes the amplitude by the given dB
+	return &effects.Volume{Streamer: streamer, Base: 10, Volume: gain / 20}
+}
+
+//analyzeSongDB analyzes all songs of the songDB which have no loudness value yet
+//this takes some time, so it should be run in its own go routine
+//the loudness db is written every loudnessSaveInterval analyzed songs and at the end instead of after every song
+//the analysis ends early when StopLoudnessAnalysis is called, the songs analyzed until then are stored
+func analyzeSongDB() {
+	analyzed := 0
+	for _, filename := range getSongDBFiles() {
+		if isLoudnessAnalysisStopped() {
+			break
+		}
+		_, ok, err := analyzeSong(filename)
+		if err != nil {
+			logging.Warn("analyzeSongDB: could not analyze song", "file", filename, "err", err)
+			continue
+		}
+		if ok {
+			analyzed++
+		}
+		if ok && analyzed%loudnessSaveInterval == 0 {
+			saveAnalyzedLoudness()
+		}
+	}
+	if analyzed%loudnessSaveInterval != 0 {
+		saveAnalyzedLoudness()
+	}
+}
+
+//saveAnalyzedLoudness writes the loudness db and logs an occurring error
+func saveAnalyzedLoudness() {
+	loudnessMutex.Lock()
+	defer loudnessMutex.Unlock()
+	err := saveLoudnessDB()
+	if err != nil {
+		logging.Error("Could not save the loudness database", "err", err)
+	}
+}
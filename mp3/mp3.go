@@ -10,7 +10,8 @@ import (
 
 	"github.com/faiface/beep"
 	"github.com/faiface/beep/mp3"
-	"github.com/faiface/beep/speaker"
+	"github.com/procrastimax/goparty/blocklist"
+	"github.com/procrastimax/goparty/logging"
 )
 
 const (
@@ -24,72 +25,134 @@ var (
 	ParenthesisRegex = regexp.MustCompile("(\\(.*\\)|\\[.*\\]|\\{.*\\}|\\<.*\\>)")
 )
 
-//CloseSpeaker closes the speaker
-func CloseSpeaker() {
-	speaker.Clear()
-	queue.Clear()
-	speaker.Close()
-	fmt.Println("Speaker closed")
+//AddMP3ToMusicQueue adds a mp3 file to the running music queue
+//the function differentiates between already downloaded/ offline songs and ones which got downloaded by youtube-dl
+//the file is not opened here, this happens shortly before the song is played
+func AddMP3ToMusicQueue(songDir, filename, userIP string, newSong bool) error {
+	filePath := songDir + filename
+	err := checkMp3File(filePath)
+	if err != nil {
+		return fmt.Errorf("load mp3: %v", err)
+	}
+
+	songName := getSongName(filename)
+	err = blocklist.CheckFile(filePath)
+	if err == nil {
+		err = blocklist.CheckTitle(songName)
+	}
+	if err != nil {
+		return err
+	}
+
+	//analyze the song already now, so the gain is most likely known when the song gets loaded for playing
+	analyzeInBackground(filePath)
+
+	queue.Add(songName, filePath, userIP, songDir != ytDownloadDir)
+
+	if newSong {
+		AddSongToDB(songDir, filename)
+	}
+
+	logging.Info("Added song to queue", "song", songName, "ip", userIP)
+	return nil
 }
 
-//PauseSpeaker pauses the speaker
-func PauseSpeaker() {
-	fmt.Println("Speaker paused")
-	queue.Pause()
+//getSongName returns the name of a song shown in the queue from its filename
+func getSongName(filename string) string {
+	songName := strings.Split(strings.Trim(filename, ".mp3"), "#____#")[0]
+	return ParenthesisRegex.ReplaceAllString(songName, "")
 }
 
-//StartSpeaker starts the speaking by using the musicqueue
-func StartSpeaker() {
-	//if queue is already initialized, then just resume playing
-	if len(queue.songs) > 0 {
-		fmt.Println("Speaker resumed")
-		queue.Resume()
-	} else {
-		speaker.Play(&queue)
-		fmt.Println("Speaker started")
+//loadSong opens and decodes the given mp3 file and prepares it for being played by the speaker
+func loadSong(filename string) (*loadedSong, error) {
+	streamer, format, err := loadMp3File(filename)
+	if err != nil {
+		return nil, fmt.Errorf("load mp3: %v", err)
 	}
+
+	// we need to resample the song sample rate to the speaker sample rate
+	resampledStreamer := beep.Resample(3, format.SampleRate, SampleRate, *streamer)
+
+	return &loadedSong{
+		streamer: applyLoudnessNormalization(filename, resampledStreamer),
+		seeker:   *streamer,
+		format:   *format,
+	}, nil
 }
 
-//InitSpeaker initializes the speaker with a fixed sample rate
-func InitSpeaker() error {
-	sr := beep.SampleRate(SampleRate)
-	err := speaker.Init(sr, sr.N(time.Second/10))
+//SkipSong skips a song in the music queue
+func SkipSong() {
+	queue.Done()
+	logging.Info("Song skipped")
+}
 
+//RemoveSong removes the song at the given position from the music queue, the currently playing song at position 0 is skipped
+func RemoveSong(songID int) error {
+	song, err := queue.Remove(songID)
 	if err != nil {
-		return fmt.Errorf("init speaker: %v", err)
+		return err
 	}
-	fmt.Println("Speaker initialized")
+	logging.Info("Removed song from queue", "song", song.SongName)
 	return nil
 }
 
-//AddMP3ToMusicQueue adds a mp3 stream to the running music queue
-//the function differentiates between already downloaded/ offline songs and ones which got downloaded by youtube-dl
-func AddMP3ToMusicQueue(songDir, filename, userIP string, newSong bool) error {
-	streamer, format, err := loadMp3File(songDir + filename)
+//MoveSong moves the song at the given position to a new position in the music queue
+func MoveSong(songID int, position int) error {
+	song, err := queue.Move(songID, position)
 	if err != nil {
-		return fmt.Errorf("load mp3: %v", err)
+		return err
 	}
+	logging.Info("Moved song", "song", song.SongName, "position", position)
+	return nil
+}
 
-	// we need to resample the song sample rate to the speaker sample rate
-	resampledStreamer := beep.Resample(3, format.SampleRate, SampleRate, *streamer)
-	speaker.Lock()
-	songName := strings.Split(strings.Trim(filename, ".mp3"), "#____#")[0]
-	songName = ParenthesisRegex.ReplaceAllString(songName, "")
-	queue.Add(songName, userIP, resampledStreamer)
+//PinSong pins the song at the given position behind the currently playing song, so it is played next, or unpins it
+func PinSong(songID int, pinned bool) error {
+	song, err := queue.Pin(songID, pinned)
+	if err != nil {
+		return err
+	}
+	if pinned {
+		logging.Info("Pinned song", "song", song.SongName)
+	} else {
+		logging.Info("Unpinned song", "song", song.SongName)
+	}
+	return nil
+}
 
-	if newSong {
-		AddSongToDB(songDir, filename)
+//RemoveUserSongs removes all songs of the user from the music queue, returns the count of removed songs
+func RemoveUserSongs(userIP string) int {
+	removed := queue.RemoveUser(userIP)
+	if removed > 0 {
+		logging.Info("Removed the songs of the user from the queue", "ip", userIP, "removed", removed)
 	}
+	return removed
+}
+
+//GetPosition returns the position and the duration of the currently playing song
+//returns false if no song is playing
+func GetPosition() (time.Duration, time.Duration, bool) {
+	return queue.Position()
+}
+
+//Seek jumps to the given position in the currently playing song
+func Seek(position time.Duration) error {
+	err := queue.Seek(position)
 
-	speaker.Unlock()
-	fmt.Printf("Added song to queue: %s\n", songName)
+	if err != nil {
+		return fmt.Errorf("seek: %v", err)
+	}
+	logging.Info("Seeked", "position", position.String())
 	return nil
 }
 
-//SkipSong skips a song in the music queue
-func SkipSong() {
-	queue.Done()
-	fmt.Println("Song skipped")
+//SeekRelative jumps forward (or backward for negative offsets) in the currently playing song
+func SeekRelative(offset time.Duration) error {
+	position, _, ok := GetPosition()
+	if ok == false {
+		return fmt.Errorf("seek: no song is playing")
+	}
+	return Seek(position + offset)
 }
 
 //GetCurrentPlaylist returns the current playlist with information about the song and who added the song
@@ -97,15 +160,44 @@ func GetCurrentPlaylist() []Song {
 	return queue.GetSongs()
 }
 
-//loadMp3File loads an mp3 file from the storage and returns it as a streamer and format
-func loadMp3File(filename string) (*beep.StreamSeekCloser, *beep.Format, error) {
+//checkMp3File checks whether the given file is an existing mp3 file
+func checkMp3File(filename string) error {
 	if len(filename) <= 4 {
-		return nil, nil, fmt.Errorf("File %s is not a valid mp3 name", filename)
+		return fmt.Errorf("File %s is not a valid mp3 name", filename)
 	}
 
 	//check if file really is an mp3
 	if filename[len(filename)-4:] != ".mp3" {
-		return nil, nil, fmt.Errorf("File %s is not a mp3", filename)
+		return fmt.Errorf("File %s is not a mp3", filename)
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+
+	if info.IsDir() {
+		return fmt.Errorf("File %s is a directory", filename)
+	}
+	return nil
+}
+
+//GetSongDuration returns the duration of the given mp3 file
+func GetSongDuration(filename string) (time.Duration, error) {
+	streamer, format, err := loadMp3File(filename)
+	if err != nil {
+		return 0, fmt.Errorf("GetSongDuration: %s", err)
+	}
+	defer (*streamer).Close()
+	return format.SampleRate.D((*streamer).Len()), nil
+}
+
+//loadMp3File loads an mp3 file from the storage and returns it as a streamer and format
+//the returned streamer needs to be closed after usage
+func loadMp3File(filename string) (*beep.StreamSeekCloser, *beep.Format, error) {
+	err := checkMp3File(filename)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	f, err := os.Open(filename)
@@ -115,6 +207,7 @@ func loadMp3File(filename string) (*beep.StreamSeekCloser, *beep.Format, error)
 
 	streamer, format, err := mp3.Decode(f)
 	if err != nil {
+		f.Close()
 		return nil, nil, err
 	}
 
@@ -4,18 +4,29 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/faiface/beep"
 	"github.com/procrastimax/goparty/clients"
+	"github.com/procrastimax/goparty/logging"
+	"github.com/procrastimax/goparty/stats"
+)
+
+const (
+	//preloadCount is the number of songs at the beginning of the queue which are kept opened
+	preloadCount = 2
 )
 
 var (
+	//neededUpvoteCount is only used while the queue is locked, so it is guarded by the queue mutex
 	neededUpvoteCount = 1
 )
 
 //SetNeededUpvoteCount sets the number of upvotes needed for a song to consider a reranking of the queue
 func SetNeededUpvoteCount(upvoteCount int) {
+	queue.Lock()
 	neededUpvoteCount = upvoteCount
+	queue.Unlock()
 }
 
 //The code for this queue comes from the beep tutorial: https://github.com/faiface/beep/wiki/Making-own-streamers
@@ -23,9 +34,14 @@ func SetNeededUpvoteCount(upvoteCount int) {
 //Song representing a single song from the downloaded queue of songs
 type Song struct {
 	SongName  string
+	FilePath  string
 	UserIP    string
 	UserName  string
 	SongCount int
+	//Offline is true when the song is from the offline song collection and was not downloaded from youtube
+	Offline bool
+	//Pinned is true when the admin pinned the song behind the currently playing song, new and upvoted songs are not put in front of it
+	Pinned bool
 	//upvotes is a list of strings, each string represents a userIP which upvoted the song
 	upvotes []string
 }
@@ -69,75 +85,267 @@ func getOnlyIP(ip *string) *string {
 	return ip
 }
 
-//songStream is a basic song with extended stream field
+//songStream is a basic song with the loaded stream of the song file
+//the file is only opened and decoded shortly before the song is played, so queued songs do not hold open files
 type songStream struct {
 	Song
-	streamer *beep.Streamer
+	id      int
+	stream  *loadedSong
+	started time.Time
+	//failed is true when the song file could not be loaded, the song is skipped when it is its turn
+	failed bool
+}
+
+//loadedSong is an opened and decoded song file which is ready for streaming
+//the seeker is the decoded file which the streamer wraps, it is kept to seek inside the song and to close the file
+type loadedSong struct {
+	streamer beep.Streamer
+	seeker   beep.StreamSeekCloser
+	format   beep.Format
+}
+
+//close closes the file of the loaded song
+func (l *loadedSong) close() {
+	err := l.seeker.Close()
+	if err != nil {
+		logging.Warn("Could not close the song", "err", err)
+	}
+}
+
+//snapshot returns a copy of the song, the upvotes are copied too, so the copy can be read while the queue changes
+func (s *songStream) snapshot() Song {
+	song := s.Song
+	song.upvotes = append([]string(nil), s.upvotes...)
+	return song
+}
+
+//finishedSong is a song which left the queue, it is recorded after the queue is unlocked, so the audio path does not wait for it
+type finishedSong struct {
+	Song
+	started time.Time
+	ended   time.Time
+	skipped bool
+}
+
+//finished returns the song for recording it after it left the queue, the queue must be locked by the caller
+func (s *songStream) finished(skipped bool) finishedSong {
+	return finishedSong{Song: s.snapshot(), started: s.started, ended: time.Now(), skipped: skipped}
+}
+
+//record marks the song as played for its user and adds it to the play history and statistics
+//songs which never started playing are not added to the history and statistics
+func (f finishedSong) record() {
+	clients.SongDonePlaying(f.UserIP)
+	if f.started.IsZero() {
+		return
+	}
+
+	stats.AddPlay(f.UserIP, f.ended.Sub(f.started), f.skipped, f.Offline)
+
+	addHistoryEntry(HistoryEntry{
+		SongName: f.SongName,
+		FilePath: f.FilePath,
+		UserIP:   f.UserIP,
+		UserName: f.UserName,
+		Upvotes:  f.GetUpvotesCount(),
+		Start:    f.started,
+		End:      f.ended,
+		Skipped:  f.skipped,
+	})
 }
 
 //MusicQueue is a datastruct to add more songs to the streamer
-// we dont need a mutex here, because this queue in only on the server side and handles one sound ouput
+//the mutex guards the songs, because songs get added, upvoted and preloaded while the speaker streams the queue
+//songs are only opened by the preload go routine, so the audio path never waits for a song file
 type MusicQueue struct {
 	songs    []songStream
 	isPaused bool
 	currIdx  int
+	nextID   int
+	//preloadRequests wakes the preload go routine up, the go routine is started with the first request
+	preloadRequests chan struct{}
+	preloadStart    sync.Once
+	//finishedSongs are the songs which left the queue and are not recorded yet, see recordFinished
+	finishedSongs []finishedSong
 	sync.Mutex
 }
 
 //GetSongs returns all songs from the music queue without streamer
 func (q *MusicQueue) GetSongs() []Song {
+	q.Lock()
+	defer q.Unlock()
 	songs := make([]Song, len(q.songs))
 	for i := range songs {
-		songs[i] = q.songs[i].Song
+		songs[i] = q.songs[i].snapshot()
 	}
 	return songs
 }
 
 //Add adds a new entry to the musicqueue
-func (q *MusicQueue) Add(songame string, userIP string, streamer beep.Streamer) {
+//the song file is not opened here, this happens when the song is one of the next songs to play
+func (q *MusicQueue) Add(songame string, filePath string, userIP string, offline bool) {
 	q.Lock()
 	clients.AddSongPlaylist(userIP)
+	stats.AddSong(userIP)
 
 	songStream := songStream{
 		Song{SongName: songame,
+			FilePath:  filePath,
+			Offline:   offline,
 			SongCount: clients.GetUserAddedSongs(userIP).PlaylistSongs,
 			UserIP:    userIP,
 			UserName:  clients.GetUserName(userIP)},
-		&streamer,
+		q.nextID,
+		nil,
+		time.Time{},
+		false,
 	}
+	q.nextID++
 
 	//like in the downloading section, add the song at the position where the count of added songs differ from the next one
-	if len(q.songs) <= 1 {
-		q.songs = append(q.songs, songStream)
-	} else {
+	//the first song is currently playing, so new songs are never inserted before it
+	//songs of the house user always yield to guest songs, guest songs are inserted before queued house songs
+	insertIdx := len(q.songs)
+	if userIP != HouseUserIP {
 		startValue := clients.GetUserAddedSongs(userIP).PlaylistSongs
-		for i, val := range q.songs {
-			if val.SongCount > startValue {
-				//when the following song has more upvotes, then skip it
-				//and check next song
-				if val.GetUpvotesCount() > 0 {
-					break
-				}
-				//Insert element at position 'i'
-				q.songs = append(q.songs, q.songs[len(q.songs)-1])
-				copy(q.songs[i+1:], q.songs[i:len(q.songs)-1])
-				q.songs[i] = songStream
+		for i := 1; i < len(q.songs); i++ {
+			if q.songs[i].Pinned {
+				continue
+			}
+			if q.songs[i].UserIP == HouseUserIP {
+				insertIdx = i
 				break
 			}
-			//when we haven't found a change yet, then also just append the song, f.e. when all songs have count of 1
-			if i == len(q.songs)-1 {
-				q.songs = append(q.songs, songStream)
+			//when the following song has upvotes, then skip it and check the next song
+			if q.songs[i].SongCount > startValue && q.songs[i].GetUpvotesCount() == 0 {
+				insertIdx = i
+				break
 			}
 		}
 	}
+
+	q.insertAt(insertIdx, songStream)
+	q.Unlock()
+
+	q.requestPreload()
+}
+
+//insertAt inserts the song at the given position, the queue must be locked by the caller
+func (q *MusicQueue) insertAt(idx int, song songStream) {
+	q.songs = append(q.songs, song)
+	copy(q.songs[idx+1:], q.songs[idx:len(q.songs)-1])
+	q.songs[idx] = song
+}
+
+//pinnedCount returns the count of pinned songs behind the currently playing song, the queue must be locked by the caller
+func (q *MusicQueue) pinnedCount() int {
+	count := 0
+	for i := 1; i < len(q.songs) && q.songs[i].Pinned; i++ {
+		count++
+	}
+	return count
+}
+
+//indexOf returns the current position of the song with the given id in the queue, -1 if the song is not in the queue
+func (q *MusicQueue) indexOf(id int) int {
+	for i := range q.songs {
+		if q.songs[i].id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+//requestPreload lets the preload go routine open the next songs of the queue, it never blocks
+//the queue can be locked by the caller
+func (q *MusicQueue) requestPreload() {
+	q.preloadStart.Do(func() {
+		q.preloadRequests = make(chan struct{}, 1)
+		go q.preloadWorker()
+	})
+	select {
+	case q.preloadRequests <- struct{}{}:
+	default:
+		//a preload is already requested and will see the current queue
+	}
+}
+
+//preloadWorker preloads the queue on every request, it runs in its own go routine
+func (q *MusicQueue) preloadWorker() {
+	for range q.preloadRequests {
+		q.preload()
+	}
+}
+
+//preload opens the next songs of the queue, so there is no gap when the next song starts playing
+//songs which got moved further back in the queue are closed again
+func (q *MusicQueue) preload() {
+	q.Lock()
+	for i := preloadCount; i < len(q.songs); i++ {
+		if q.songs[i].stream != nil {
+			q.songs[i].stream.close()
+			q.songs[i].stream = nil
+		}
+	}
 	q.Unlock()
+
+	for i := 0; i < preloadCount; i++ {
+		q.Lock()
+		if i >= len(q.songs) {
+			q.Unlock()
+			return
+		}
+		if q.songs[i].stream != nil || q.songs[i].failed {
+			q.Unlock()
+			continue
+		}
+		id, filePath := q.songs[i].id, q.songs[i].FilePath
+		q.Unlock()
+
+		//the gain is needed when loading the song, the analysis decodes the whole song, so it is done here and never in the audio path
+		analyzeSongNow(filePath)
+
+		//loading takes some time, so the queue is not locked meanwhile
+		stream, err := loadSong(filePath)
+		if err != nil {
+			logging.Warn("Could not preload the song", "file", filePath, "err", err)
+			q.Lock()
+			if idx := q.indexOf(id); idx >= 0 {
+				q.songs[idx].failed = true
+			}
+			q.Unlock()
+			continue
+		}
+
+		q.Lock()
+		idx := q.indexOf(id)
+		if idx < 0 || q.songs[idx].stream != nil {
+			//the song was removed or loaded in the meantime
+			stream.close()
+		} else {
+			q.songs[idx].stream = stream
+		}
+		q.Unlock()
+	}
 }
 
 //UpvoteSong adds a user to the upvoted song specified by the songID which is the current ID of the song in the queue
 func (q *MusicQueue) UpvoteSong(songID int, userIP string) {
 	q.Lock()
 	defer q.Unlock()
+	q.upvoteSong(songID, userIP)
+}
+
+//upvoteSong upvotes the song at the given position in the queue, the queue must be locked by the caller
+func (q *MusicQueue) upvoteSong(songID int, userIP string) {
+	if songID < 0 || songID >= len(q.songs) {
+		return
+	}
+	upvotes := q.songs[songID].GetUpvotesCount()
 	q.songs[songID].Upvote(userIP)
+	if q.songs[songID].GetUpvotesCount() > upvotes {
+		stats.AddVote(userIP, q.songs[songID].SongName, q.songs[songID].UserIP)
+	}
 
 	//after upvoting the song we want to decrease the added count, so the song moves forward in the queue
 	//therefore we need to check the element before the upvoted song
@@ -147,6 +355,11 @@ func (q *MusicQueue) UpvoteSong(songID int, userIP string) {
 		return
 	}
 
+	//upvoted songs never pass pinned songs
+	if q.songs[songID-1].Pinned {
+		return
+	}
+
 	//check song before current song, if the song before this song has a different SongCount value
 	//then we need to decrease the songcount for this song so when adding new songs we have coherent values
 	if q.songs[songID-1].SongCount == q.songs[songID].SongCount {
@@ -181,9 +394,20 @@ func (q *MusicQueue) CheckUserUpvotedSong(songID int, userIP string) bool {
 //Done skips to the next song
 func (q *MusicQueue) Done() {
 	q.Lock()
+	q.done(true)
+	q.Unlock()
+	q.recordFinished()
+}
+
+//done removes the current song from the queue and closes its file, the queue must be locked by the caller
+//the song is recorded by recordFinished, which the caller must call after unlocking the queue
+func (q *MusicQueue) done(skipped bool) {
 	if len(q.songs) > 0 {
+		if q.songs[0].stream != nil {
+			q.songs[0].stream.close()
+		}
+		q.finishedSongs = append(q.finishedSongs, q.songs[0].finished(skipped))
 		userIP := q.songs[0].UserIP
-		clients.SongDonePlaying(userIP)
 		q.songs = q.songs[1:]
 		q.currIdx++
 		//we need to iterate over the complete queue, and decrease the count of the user added songs
@@ -194,27 +418,231 @@ func (q *MusicQueue) Done() {
 				}
 			}
 		}
+		q.requestPreload()
 	}
+}
+
+//recordFinished records the songs which left the queue, the queue must not be locked by the caller
+func (q *MusicQueue) recordFinished() {
+	q.Lock()
+	finished := q.finishedSongs
+	q.finishedSongs = nil
 	q.Unlock()
+
+	for _, song := range finished {
+		song.record()
+	}
+}
+
+//Remove removes the song at the given position from the queue, the currently playing song at position 0 is skipped
+func (q *MusicQueue) Remove(songID int) (Song, error) {
+	q.Lock()
+	defer q.Unlock()
+	if songID < 0 || songID >= len(q.songs) {
+		return Song{}, fmt.Errorf("Remove: there is no song at position %d", songID)
+	}
+	song := q.songs[songID].snapshot()
+	if songID == 0 {
+		q.done(true)
+		//the deferred unlock runs before the recording
+		defer q.recordFinished()
+		return song, nil
+	}
+
+	if q.songs[songID].stream != nil {
+		q.songs[songID].stream.close()
+	}
+	clients.SongDonePlaying(song.UserIP)
+	q.songs = append(q.songs[:songID], q.songs[songID+1:]...)
+
+	//the following songs of the user move forward
+	for i := songID; i < len(q.songs); i++ {
+		if q.songs[i].UserIP == song.UserIP && q.songs[i].SongCount > 0 {
+			q.songs[i].SongCount--
+		}
+	}
+	q.requestPreload()
+	return song, nil
+}
+
+//Move moves the waiting song at the given position to a new position, the currently playing song cannot be moved
+//and no song can be moved in front of it, positions behind the end of the queue move the song to the end
+//songs which are not pinned cannot be moved in front of pinned songs and pinned songs stay in front of all other songs
+func (q *MusicQueue) Move(songID int, position int) (Song, error) {
+	q.Lock()
+	defer q.Unlock()
+	if songID < 1 || songID >= len(q.songs) {
+		return Song{}, fmt.Errorf("Move: there is no waiting song at position %d", songID)
+	}
+	if position < 1 {
+		return Song{}, fmt.Errorf("Move: songs cannot be moved to position %d in front of the currently playing song", position)
+	}
+
+	pinned := q.pinnedCount()
+	if position >= len(q.songs) {
+		position = len(q.songs) - 1
+	}
+	if q.songs[songID].Pinned && position > pinned {
+		position = pinned
+	} else if q.songs[songID].Pinned == false && position <= pinned {
+		position = pinned + 1
+	}
+
+	song := q.songs[songID]
+	q.songs = append(q.songs[:songID], q.songs[songID+1:]...)
+	q.insertAt(position, song)
+	q.requestPreload()
+	return song.snapshot(), nil
+}
+
+//Pin pins the waiting song at the given position behind the currently playing song and the other pinned songs
+//unpinned songs are put behind the remaining pinned songs and can be passed by upvoted songs again
+func (q *MusicQueue) Pin(songID int, pinned bool) (Song, error) {
+	q.Lock()
+	defer q.Unlock()
+	if songID < 1 || songID >= len(q.songs) {
+		return Song{}, fmt.Errorf("Pin: there is no waiting song at position %d", songID)
+	}
+
+	song := q.songs[songID]
+	song.Pinned = pinned
+	q.songs = append(q.songs[:songID], q.songs[songID+1:]...)
+	q.insertAt(q.pinnedCount()+1, song)
+	q.requestPreload()
+	return song.snapshot(), nil
+}
+
+//RemoveUser removes all songs of the user from the queue, the currently playing song is skipped when it belongs to the user
+//returns the count of removed songs
+func (q *MusicQueue) RemoveUser(userIP string) int {
+	//the deferred recording runs after the deferred unlock
+	defer q.recordFinished()
+	q.Lock()
+	defer q.Unlock()
+
+	removed := 0
+	songs := make([]songStream, 0, len(q.songs))
+	for i := range q.songs {
+		if i == 0 || q.songs[i].UserIP != userIP {
+			songs = append(songs, q.songs[i])
+			continue
+		}
+		if q.songs[i].stream != nil {
+			q.songs[i].stream.close()
+		}
+		clients.SongDonePlaying(userIP)
+		removed++
+	}
+	q.songs = songs
+
+	if len(q.songs) > 0 && q.songs[0].UserIP == userIP {
+		q.done(true)
+		removed++
+	} else if removed > 0 {
+		q.requestPreload()
+	}
+	return removed
+}
+
+//Current returns the currently playing song and its id, the id changes whenever the queue advances
+//returns false if there is no song in the queue
+func (q *MusicQueue) Current() (Song, int, bool) {
+	q.Lock()
+	defer q.Unlock()
+	if len(q.songs) == 0 {
+		return Song{}, 0, false
+	}
+	return q.songs[0].snapshot(), q.songs[0].id, true
+}
+
+//Position returns the current position and the duration of the currently playing song
+//returns false if there is no song in the queue or the song is not loaded yet
+func (q *MusicQueue) Position() (time.Duration, time.Duration, bool) {
+	q.Lock()
+	defer q.Unlock()
+	if len(q.songs) == 0 || q.songs[0].stream == nil {
+		return 0, 0, false
+	}
+	song := q.songs[0].stream
+	return song.format.SampleRate.D(song.seeker.Position()), song.format.SampleRate.D(song.seeker.Len()), true
+}
+
+//Seek sets the position of the currently playing song, the position is limited to the song duration
+func (q *MusicQueue) Seek(position time.Duration) error {
+	q.Lock()
+	defer q.Unlock()
+	if len(q.songs) == 0 || q.songs[0].stream == nil {
+		return fmt.Errorf("Seek: no song is playing")
+	}
+	song := q.songs[0].stream
+
+	sample := song.format.SampleRate.N(position)
+	if sample < 0 {
+		sample = 0
+	} else if sample >= song.seeker.Len() {
+		sample = song.seeker.Len() - 1
+	}
+	return song.seeker.Seek(sample)
 }
 
 //Pause pauses the music
 func (q *MusicQueue) Pause() {
+	q.Lock()
 	q.isPaused = true
+	q.Unlock()
 }
 
 //Resume resumes music
 func (q *MusicQueue) Resume() {
+	q.Lock()
 	q.isPaused = false
+	q.Unlock()
 }
 
-//Clear deletes all entries in the music queue
+//Clear deletes all entries in the music queue and closes all opened songs
+//the currently playing song was interrupted, it is recorded as skipped
 func (q *MusicQueue) Clear() {
-	q = &MusicQueue{}
+	q.Lock()
+	for i := range q.songs {
+		if q.songs[i].stream != nil {
+			q.songs[i].stream.close()
+		}
+		q.finishedSongs = append(q.finishedSongs, q.songs[i].finished(true))
+	}
+	q.songs = nil
+	q.Unlock()
+	q.recordFinished()
+}
+
+//Unload closes all opened songs but keeps them in the queue, they get opened again when they are played
+func (q *MusicQueue) Unload() {
+	q.Lock()
+	defer q.Unlock()
+	for i := range q.songs {
+		if q.songs[i].stream != nil {
+			q.songs[i].stream.close()
+			q.songs[i].stream = nil
+		}
+	}
 }
 
 //Stream implements the streamer interface
+//the finished songs are recorded and autoplay is triggered after the queue is unlocked, so other go routines do not wait for it
 func (q *MusicQueue) Stream(samples [][2]float64) (n int, ok bool) {
+	q.Lock()
+	lastSong := q.stream(samples)
+	q.Unlock()
+
+	q.recordFinished()
+	if lastSong {
+		triggerAutoplay()
+	}
+	return len(samples), true
+}
+
+//stream fills the samples from the songs of the queue, the queue must be locked by the caller
+//returns true when no song follows the playing song or the queue ran empty
+func (q *MusicQueue) stream(samples [][2]float64) bool {
 	// successfully filled already. We loop until all samples are filled.
 	filled := 0
 	for filled < len(samples) {
@@ -222,23 +650,43 @@ func (q *MusicQueue) Stream(samples [][2]float64) (n int, ok bool) {
 		// If the isPaused flag is set, we also stream silence
 		if len(q.songs) == 0 || q.isPaused {
 			for i := range samples[filled:] {
-				samples[i][0] = 0
-				samples[i][1] = 0
+				samples[filled+i][0] = 0
+				samples[filled+i][1] = 0
 			}
 			break
 		}
 
+		if q.songs[0].failed {
+			logging.Warn("Could not play the song", "song", q.songs[0].SongName)
+			q.done(true)
+			continue
+		}
+
+		// The song was not preloaded in time, loading it here would block the output, so we stream silence until it is loaded
+		if q.songs[0].stream == nil {
+			q.requestPreload()
+			for i := range samples[filled:] {
+				samples[filled+i][0] = 0
+				samples[filled+i][1] = 0
+			}
+			break
+		}
+
+		if q.songs[0].started.IsZero() {
+			q.songs[0].started = time.Now()
+		}
+
 		// We stream from the first streamer in the queue.
-		n, ok := (*q.songs[0].streamer).Stream(samples[filled:])
+		n, ok := q.songs[0].stream.streamer.Stream(samples[filled:])
 		// If it's drained, we pop it from the queue, thus continuing with
 		// the next streamer.
 		if !ok {
-			q.Done()
+			q.done(false)
 		}
 		// We update the number of filled samples.
 		filled += n
 	}
-	return len(samples), true
+	return len(q.songs) < 2
 }
 
 //Err trivial error implementation
@@ -0,0 +1,371 @@
+package mp3
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/speaker"
+
+	"github.com/procrastimax/goparty/logging"
+)
+
+/**
+	Audio outputs the player can stream the music queue to.
+	The speaker output plays the music on the local sound card, the null output discards all samples
+	but still advances the queue in real time (f.e. for servers without sound card or for testing)
+	and the wav output records everything which is played into a wav file.
+**/
+
+const (
+	//outputBufferDuration is the duration of the samples an output pulls at once
+	outputBufferDuration = time.Second / 10
+)
+
+var (
+	output Output = NewSpeakerOutput()
+	//outputMutex guards the master volume and the fade, which get changed while the output streams them
+	outputMutex sync.Mutex
+	//fadeSamples is the length of the fade out in samples, 0 when the music is not fading out
+	fadeSamples int
+	//fadeRemaining is the count of samples until the music is silent
+	fadeRemaining int
+	//outputErr is the error of the failed output which stopped the player, it is guarded by the player mutex
+	outputErr error
+	//playerStream is the streamer every output plays, the played samples are also sent to all stream listeners
+	playerStream = beep.StreamerFunc(func(samples [][2]float64) (int, bool) {
+		outputMutex.Lock()
+		defer outputMutex.Unlock()
+		n, ok := masterVolume.Stream(samples)
+		applyFade(samples[:n])
+		broadcast(samples[:n])
+		return n, ok
+	})
+)
+
+//applyFade lowers the volume of the samples while the music fades out, the output mutex must be locked by the caller
+func applyFade(samples [][2]float64) {
+	if fadeSamples == 0 {
+		return
+	}
+	for i := range samples {
+		gain := float64(fadeRemaining) / float64(fadeSamples)
+		samples[i][0] *= gain
+		samples[i][1] *= gain
+		if fadeRemaining > 0 {
+			fadeRemaining--
+		}
+	}
+}
+
+//Output is an audio output the player streams the music to
+type Output interface {
+	//Start starts pulling samples from the streamer with the given sample rate
+	Start(sampleRate beep.SampleRate, streamer beep.Streamer) error
+	//Stop stops pulling samples and releases the output, the output can be started again afterwards
+	Stop() error
+}
+
+//OutputFailed stops the player when the running output cannot continue, f.e. when the recording cannot be written anymore
+//outputs call it after they stopped pulling samples, the error is kept until the player is started again
+func OutputFailed(err error) {
+	playerMutex.Lock()
+	defer playerMutex.Unlock()
+
+	if playerState == Stopped {
+		return
+	}
+	logging.Error("Output failed, the player is stopped", "err", err)
+
+	stopErr := output.Stop()
+	if stopErr != nil {
+		logging.Error("Could not stop the output", "err", stopErr)
+	}
+	playerState = Stopped
+	outputErr = err
+}
+
+//GetOutputError returns the error which stopped the player, nil when the output did not fail since the last start
+func GetOutputError() error {
+	playerMutex.Lock()
+	defer playerMutex.Unlock()
+	return outputErr
+}
+
+//SetOutput sets the output used by the player, the output can only be changed while the player is stopped
+func SetOutput(o Output) error {
+	playerMutex.Lock()
+	defer playerMutex.Unlock()
+
+	if playerState != Stopped {
+		return fmt.Errorf("SetOutput: the output can only be changed while the player is stopped")
+	}
+	output = o
+	return nil
+}
+
+//speakerOutput plays the music on the local speaker
+type speakerOutput struct{}
+
+//NewSpeakerOutput returns an output which plays the music on the local speaker
+func NewSpeakerOutput() Output {
+	return &speakerOutput{}
+}
+
+func (s *speakerOutput) Start(sampleRate beep.SampleRate, streamer beep.Streamer) error {
+	err := speaker.Init(sampleRate, sampleRate.N(outputBufferDuration))
+	if err != nil {
+		return err
+	}
+	speaker.Play(streamer)
+	return nil
+}
+
+func (s *speakerOutput) Stop() error {
+	speaker.Clear()
+	speaker.Close()
+	return nil
+}
+
+//clockedOutput pulls samples from a streamer in real time, it is used by all outputs without a sound card
+type clockedOutput struct {
+	quit chan struct{}
+	done chan struct{}
+}
+
+//start pulls the samples in real time and passes them to the write function until stop is called
+func (c *clockedOutput) start(sampleRate beep.SampleRate, streamer beep.Streamer, write func(samples [][2]float64) error) {
+	c.quit = make(chan struct{})
+	c.done = make(chan struct{})
+	samples := make([][2]float64, sampleRate.N(outputBufferDuration))
+
+	go func() {
+		err := c.run(streamer, samples, write)
+		close(c.done)
+		if err != nil {
+			OutputFailed(err)
+		}
+	}()
+}
+
+//run pulls the samples until stop is called or writing the samples failed
+func (c *clockedOutput) run(streamer beep.Streamer, samples [][2]float64, write func(samples [][2]float64) error) error {
+	ticker := time.NewTicker(outputBufferDuration)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.quit:
+			return nil
+		case <-ticker.C:
+			streamer.Stream(samples)
+			err := write(samples)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+//stop stops pulling samples and waits until the last samples are written
+func (c *clockedOutput) stop() {
+	if c.quit == nil {
+		return
+	}
+	close(c.quit)
+	<-c.done
+	c.quit = nil
+}
+
+//nullOutput discards all samples
+type nullOutput struct {
+	clockedOutput
+}
+
+//NewNullOutput returns an output which discards all samples, but still plays the queue in real time
+func NewNullOutput() Output {
+	return &nullOutput{}
+}
+
+func (n *nullOutput) Start(sampleRate beep.SampleRate, streamer beep.Streamer) error {
+	n.start(sampleRate, streamer, func(samples [][2]float64) error {
+		return nil
+	})
+	return nil
+}
+
+func (n *nullOutput) Stop() error {
+	n.stop()
+	return nil
+}
+
+//wavOutput records all samples into a 16 bit stereo wav file
+//the sizes in a wav header are 32 bit, so a recording longer than about 6.7 hours is continued in a new numbered file
+type wavOutput struct {
+	clockedOutput
+	path string
+	//recordPath is the path of the first file of the recording, part is the number of the current file
+	recordPath string
+	part       int
+	file       *os.File
+	format     beep.Format
+	dataBytes  uint32
+}
+
+//NewWAVOutput returns an output which records the music into a wav file at the given path
+//when the file already exists, the current time is added to the file name, so no recording gets overwritten
+func NewWAVOutput(path string) Output {
+	return &wavOutput{path: path}
+}
+
+const (
+	//wavHeaderSize is the size of the RIFF header of a wav file with a PCM format chunk
+	wavHeaderSize = 44
+	//maxWAVDataBytes is the maximum size of the sample data of a wav file, so the file size still fits into the 32 bit size of the header
+	maxWAVDataBytes = math.MaxUint32 - (wavHeaderSize - 8)
+)
+
+func (w *wavOutput) Start(sampleRate beep.SampleRate, streamer beep.Streamer) error {
+	path := w.path
+	if _, err := os.Stat(path); err == nil {
+		ext := filepath.Ext(path)
+		path = strings.TrimSuffix(path, ext) + "-" + time.Now().Format("20060102-150405") + ext
+	}
+
+	w.recordPath = path
+	w.part = 1
+	w.format = beep.Format{SampleRate: sampleRate, NumChannels: 2, Precision: 2}
+	err := w.openFile(path)
+	if err != nil {
+		return fmt.Errorf("wav output: %s", err)
+	}
+
+	buf := make([]byte, sampleRate.N(outputBufferDuration)*w.format.Width())
+	w.start(sampleRate, streamer, func(samples [][2]float64) error {
+		n := 0
+		for _, sample := range samples {
+			n += w.format.EncodeSigned(buf[n:], sample)
+		}
+		if uint64(w.dataBytes)+uint64(n) > maxWAVDataBytes {
+			err := w.nextFile()
+			if err != nil {
+				return fmt.Errorf("wav output: %s", err)
+			}
+		}
+		_, err := w.file.Write(buf[:n])
+		w.dataBytes += uint32(n)
+		return err
+	})
+	logging.Info("Recording music", "path", path)
+	return nil
+}
+
+//openFile creates the wav file at the given path and writes the header, the sizes in the header are written again when the file is finished
+func (w *wavOutput) openFile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	w.file = file
+	w.dataBytes = 0
+	err = w.writeHeader()
+	if err != nil {
+		file.Close()
+		w.file = nil
+		return err
+	}
+	return nil
+}
+
+//finishFile writes the sizes of the recorded data into the header and closes the file
+func (w *wavOutput) finishFile() error {
+	_, err := w.file.Seek(0, 0)
+	if err == nil {
+		err = w.writeHeader()
+	}
+	closeErr := w.file.Close()
+	w.file = nil
+
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
+
+//nextFile finishes the current file and continues the recording in a new file, f.e. party-2.wav after party.wav
+func (w *wavOutput) nextFile() error {
+	err := w.finishFile()
+	if err != nil {
+		return err
+	}
+
+	w.part++
+	ext := filepath.Ext(w.recordPath)
+	path := fmt.Sprintf("%s-%d%s", strings.TrimSuffix(w.recordPath, ext), w.part, ext)
+	err = w.openFile(path)
+	if err != nil {
+		return err
+	}
+	logging.Info("Continuing the recording in a new file, because the wav file is full", "path", path)
+	return nil
+}
+
+func (w *wavOutput) Stop() error {
+	w.stop()
+	if w.file == nil {
+		return nil
+	}
+
+	err := w.finishFile()
+	if err != nil {
+		return fmt.Errorf("wav output: %s", err)
+	}
+	return nil
+}
+
+//writeHeader writes the RIFF header of the wav file with the currently recorded data size
+func (w *wavOutput) writeHeader() error {
+	return writeWAVHeader(w.file, w.format, w.dataBytes)
+}
+
+//writeWAVHeader writes the RIFF header of a wav file with the given format and size of the sample data
+func writeWAVHeader(w io.Writer, format beep.Format, dataBytes uint32) error {
+	header := struct {
+		RiffMark      [4]byte
+		FileSize      uint32
+		WaveMark      [4]byte
+		FmtMark       [4]byte
+		FormatSize    uint32
+		FormatType    uint16
+		NumChans      uint16
+		SampleRate    uint32
+		ByteRate      uint32
+		BytesPerFrame uint16
+		BitsPerSample uint16
+		DataMark      [4]byte
+		DataSize      uint32
+	}{
+		RiffMark:      [4]byte{'R', 'I', 'F', 'F'},
+		FileSize:      wavHeaderSize - 8 + dataBytes,
+		WaveMark:      [4]byte{'W', 'A', 'V', 'E'},
+		FmtMark:       [4]byte{'f', 'm', 't', ' '},
+		FormatSize:    16,
+		FormatType:    1,
+		NumChans:      uint16(format.NumChannels),
+		SampleRate:    uint32(format.SampleRate),
+		ByteRate:      uint32(int(format.SampleRate) * format.Width()),
+		BytesPerFrame: uint16(format.Width()),
+		BitsPerSample: uint16(format.Precision * 8),
+		DataMark:      [4]byte{'d', 'a', 't', 'a'},
+		DataSize:      dataBytes,
+	}
+	return binary.Write(w, binary.LittleEndian, header)
+}
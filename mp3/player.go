@@ -0,0 +1,167 @@
+package mp3
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/faiface/beep"
+
+	"github.com/procrastimax/goparty/logging"
+)
+
+/**
+	The player is a small state machine around the speaker and the music queue:
+
+	Stopped --Start--> Playing --Pause--> Paused --Resume--> Playing
+	Playing/Paused --Stop--> Stopped
+
+	When stopping, the output (f.e. the speaker) is closed and gets initialized again on the next start.
+**/
+
+//PlayerState is the current state of the player
+type PlayerState int
+
+const (
+	//Stopped means the speaker is closed and nothing is played
+	Stopped PlayerState = iota
+	//Playing means the speaker plays the music queue
+	Playing
+	//Paused means the speaker is initialized but plays silence until the player is resumed
+	Paused
+)
+
+var (
+	playerState = Stopped
+	playerMutex sync.Mutex
+)
+
+func (s PlayerState) String() string {
+	switch s {
+	case Stopped:
+		return "stopped"
+	case Playing:
+		return "playing"
+	case Paused:
+		return "paused"
+	}
+	return "unknown"
+}
+
+//GetPlayerState returns the current state of the player
+func GetPlayerState() PlayerState {
+	playerMutex.Lock()
+	defer playerMutex.Unlock()
+	return playerState
+}
+
+//Start initializes the output and starts playing the music queue
+//a paused player gets resumed, starting an already playing player does nothing
+func Start() error {
+	playerMutex.Lock()
+	defer playerMutex.Unlock()
+
+	switch playerState {
+	case Playing:
+		return nil
+	case Paused:
+		queue.Resume()
+		playerState = Playing
+		logging.Info("Speaker resumed")
+		return nil
+	}
+
+	queue.Resume()
+	//a previous fade out ends with the stop, the music plays with the normal volume again
+	outputMutex.Lock()
+	fadeSamples, fadeRemaining = 0, 0
+	outputMutex.Unlock()
+	outputErr = nil
+	err := output.Start(beep.SampleRate(SampleRate), playerStream)
+	if err != nil {
+		return fmt.Errorf("start output: %v", err)
+	}
+
+	playerState = Playing
+	logging.Info("Speaker started")
+	return nil
+}
+
+//Pause pauses the playing music, the speaker keeps running and plays silence
+func Pause() error {
+	playerMutex.Lock()
+	defer playerMutex.Unlock()
+
+	switch playerState {
+	case Paused:
+		return nil
+	case Stopped:
+		return fmt.Errorf("pause: player is stopped")
+	}
+
+	queue.Pause()
+	playerState = Paused
+	logging.Info("Speaker paused")
+	return nil
+}
+
+//Resume resumes the paused music
+func Resume() error {
+	playerMutex.Lock()
+	defer playerMutex.Unlock()
+
+	switch playerState {
+	case Playing:
+		return nil
+	case Stopped:
+		return fmt.Errorf("resume: player is stopped")
+	}
+
+	queue.Resume()
+	playerState = Playing
+	logging.Info("Speaker resumed")
+	return nil
+}
+
+//FadeOut fades the playing music out over the given duration and returns when the music is silent
+//the music stays silent until the player is stopped and started again
+func FadeOut(duration time.Duration) {
+	if GetPlayerState() != Playing {
+		return
+	}
+
+	outputMutex.Lock()
+	fadeSamples = beep.SampleRate(SampleRate).N(duration)
+	fadeRemaining = fadeSamples
+	outputMutex.Unlock()
+
+	//the output pulls the samples ahead of playing them
+	time.Sleep(duration + outputBufferDuration)
+}
+
+//Stop stops the music and closes the output, the output is initialized again on the next start
+//when clearQueue is set, all songs are removed from the queue, otherwise the queue is kept
+//and the current song starts from the beginning on the next start
+func Stop(clearQueue bool) {
+	playerMutex.Lock()
+	defer playerMutex.Unlock()
+
+	if playerState != Stopped {
+		err := output.Stop()
+		if err != nil {
+			logging.Error("Could not stop the output", "err", err)
+		}
+		playerState = Stopped
+		logging.Info("Speaker closed")
+	}
+
+	if clearQueue {
+		queue.Clear()
+		logging.Info("Queue cleared")
+	} else {
+		queue.Unload()
+	}
+
+	//the finished songs are written to the history file in the background
+	waitForHistory()
+}
@@ -0,0 +1,209 @@
+package mp3
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/**
+	Import and export of playlist files.
+	M3U/M3U8 and PLS playlists can be imported, their entries are resolved against the songDB.
+	Playlists which were created on another machine mostly contain other paths, so entries are also matched by their filename.
+**/
+
+//PlaylistEntry is a single song of a playlist file
+type PlaylistEntry struct {
+	Title string
+	Path  string
+	//Length is the length of the song in seconds, -1 when unknown
+	Length int
+}
+
+//ReadM3U reads a m3u playlist and returns the paths of all entries
+//relative paths are resolved against the directory of the playlist, comments and extended m3u tags are ignored
+func ReadM3U(filename string) ([]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("ReadM3U: %s", err)
+	}
+	defer file.Close()
+
+	entries, err := ParseM3U(file, filepath.Dir(filename))
+	if err != nil {
+		return nil, fmt.Errorf("ReadM3U: %s", err)
+	}
+	return entries, nil
+}
+
+//ParseM3U parses a m3u or m3u8 playlist and returns the paths of all entries
+//relative paths are resolved against baseDir, when baseDir is empty they are kept relative
+func ParseM3U(r io.Reader, baseDir string) ([]string, error) {
+	entries := make([]string, 0)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		//remove a possible utf-8 byte order mark of m3u8 files
+		line = strings.TrimPrefix(line, "\ufeff")
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, resolveEntryPath(line, baseDir))
+	}
+
+	if scanner.Err() != nil {
+		return nil, fmt.Errorf("ParseM3U: %s", scanner.Err())
+	}
+	return entries, nil
+}
+
+//ParsePLS parses a pls playlist and returns the paths of all entries ordered by their number
+//relative paths are resolved against baseDir, when baseDir is empty they are kept relative
+func ParsePLS(r io.Reader, baseDir string) ([]string, error) {
+	files := make(map[int]string)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(strings.ToLower(line), "file") == false {
+			continue
+		}
+
+		split := strings.SplitN(line, "=", 2)
+		if len(split) != 2 {
+			continue
+		}
+		number, err := strconv.Atoi(strings.TrimSpace(split[0][len("file"):]))
+		if err != nil {
+			continue
+		}
+		files[number] = resolveEntryPath(strings.TrimSpace(split[1]), baseDir)
+	}
+
+	if scanner.Err() != nil {
+		return nil, fmt.Errorf("ParsePLS: %s", scanner.Err())
+	}
+
+	numbers := make([]int, 0, len(files))
+	for number := range files {
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+
+	entries := make([]string, 0, len(numbers))
+	for _, number := range numbers {
+		entries = append(entries, files[number])
+	}
+	return entries, nil
+}
+
+//ParsePlaylist parses a m3u, m3u8 or pls playlist, the format is taken from the extension of the filename
+func ParsePlaylist(r io.Reader, filename string, baseDir string) ([]string, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".m3u", ".m3u8":
+		return ParseM3U(r, baseDir)
+	case ".pls":
+		return ParsePLS(r, baseDir)
+	}
+	return nil, fmt.Errorf("ParsePlaylist: %s is not a m3u, m3u8 or pls playlist", filename)
+}
+
+//resolveEntryPath removes a file url prefix and resolves a relative path against the baseDir
+func resolveEntryPath(entry string, baseDir string) string {
+	entry = strings.TrimPrefix(entry, "file://")
+	if len(baseDir) > 0 && filepath.IsAbs(entry) == false && isWindowsPath(entry) == false {
+		entry = filepath.Join(baseDir, entry)
+	}
+	return entry
+}
+
+//isWindowsPath returns true for absolute windows paths like C:\Music\song.mp3
+func isWindowsPath(path string) bool {
+	return len(path) > 2 && path[1] == ':' && (path[2] == '\\' || path[2] == '/')
+}
+
+//entryFilename returns the filename of a playlist entry, entries can contain windows or unix paths
+func entryFilename(entry string) string {
+	entry = strings.Replace(entry, "\\", "/", -1)
+	return entry[strings.LastIndex(entry, "/")+1:]
+}
+
+//ResolvePlaylistEntry searches the song of a playlist entry in the songDB and returns its directory and filename
+//entries are matched by their complete path first, then by their filename
+func ResolvePlaylistEntry(entry string) (string, string, bool) {
+	filename := entryFilename(entry)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	matchDir := ""
+	for dir, songs := range songDB {
+		for _, song := range songs {
+			if dir+song == entry {
+				return dir, song, true
+			}
+			if len(matchDir) == 0 && strings.EqualFold(song, filename) {
+				matchDir = dir
+				filename = song
+			}
+		}
+	}
+
+	if len(matchDir) > 0 {
+		return matchDir, filename, true
+	}
+	return "", "", false
+}
+
+//ImportPlaylist adds all entries of a playlist which are in the songDB to the music queue for the given user
+//returns the count of added songs and the entries which could not be added
+func ImportPlaylist(entries []string, userIP string) (int, []string) {
+	added := 0
+	missing := make([]string, 0)
+	for _, entry := range entries {
+		dir, filename, ok := ResolvePlaylistEntry(entry)
+		if ok == false {
+			missing = append(missing, entry)
+			continue
+		}
+
+		err := AddMP3ToMusicQueue(dir, filename, userIP, false)
+		if err != nil {
+			missing = append(missing, entry)
+			continue
+		}
+		added++
+	}
+	return added, missing
+}
+
+//GetQueueEntries returns the songs of the current music queue as playlist entries
+func GetQueueEntries() []PlaylistEntry {
+	entries := make([]PlaylistEntry, 0)
+	for _, song := range queue.GetSongs() {
+		entries = append(entries, PlaylistEntry{Title: song.SongName, Path: song.FilePath, Length: -1})
+	}
+	return entries
+}
+
+//WriteM3U writes the given entries as extended m3u playlist
+func WriteM3U(w io.Writer, entries []PlaylistEntry) error {
+	_, err := fmt.Fprintln(w, "#EXTM3U")
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		_, err = fmt.Fprintf(w, "#EXTINF:%d,%s\n%s\n", entry.Length, entry.Title, entry.Path)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package mp3
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/procrastimax/goparty/clients"
+	"github.com/procrastimax/goparty/logging"
+)
+
+/**
+	The music queue is saved into a json file when the program shuts down and restored on the next start.
+	The currently playing song starts from the beginning, songs whose files were deleted meanwhile are dropped.
+**/
+
+//savedSong is a song of the music queue as it is saved in the queue file
+type savedSong struct {
+	SongName string   `json:"song"`
+	FilePath string   `json:"file"`
+	UserIP   string   `json:"userIP"`
+	UserName string   `json:"userName"`
+	Offline  bool     `json:"offline"`
+	Pinned   bool     `json:"pinned"`
+	Upvotes  []string `json:"upvotes"`
+}
+
+//SaveQueue writes all songs of the music queue into the given json file, an empty queue removes the file
+func SaveQueue(path string) error {
+	queue.Lock()
+	songs := make([]savedSong, len(queue.songs))
+	for i, song := range queue.songs {
+		songs[i] = savedSong{song.SongName, song.FilePath, song.UserIP, song.UserName, song.Offline, song.Pinned, song.GetUpvotes()}
+	}
+	queue.Unlock()
+
+	if len(songs) == 0 {
+		err := os.Remove(path)
+		if err != nil && os.IsNotExist(err) == false {
+			return fmt.Errorf("SaveQueue: %s", err)
+		}
+		return nil
+	}
+
+	file, err := json.MarshalIndent(songs, "", " ")
+	if err != nil {
+		return fmt.Errorf("SaveQueue: %s", err)
+	}
+	err = ioutil.WriteFile(path, file, 0644)
+	if err != nil {
+		return fmt.Errorf("SaveQueue: %s", err)
+	}
+	return nil
+}
+
+//RestoreQueue appends the songs saved in the given json file to the music queue in their saved order and removes the file
+//returns the count of restored songs, a missing file restores nothing
+func RestoreQueue(path string) (int, error) {
+	file, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("RestoreQueue: %s", err)
+	}
+
+	var songs []savedSong
+	err = json.Unmarshal(file, &songs)
+	if err != nil {
+		return 0, fmt.Errorf("RestoreQueue: %s", err)
+	}
+
+	restored := 0
+	queue.Lock()
+	for _, saved := range songs {
+		if checkMp3File(saved.FilePath) != nil {
+			logging.Warn("Not restoring the song, the file does not exist anymore", "song", saved.SongName, "file", saved.FilePath)
+			continue
+		}
+
+		clients.AddSongPlaylist(saved.UserIP)
+		song := songStream{
+			Song{SongName: saved.SongName,
+				FilePath:  saved.FilePath,
+				Offline:   saved.Offline,
+				Pinned:    saved.Pinned,
+				SongCount: clients.GetUserAddedSongs(saved.UserIP).PlaylistSongs,
+				UserIP:    saved.UserIP,
+				UserName:  saved.UserName,
+				upvotes:   saved.Upvotes},
+			queue.nextID,
+			nil,
+			time.Time{},
+			false,
+		}
+		queue.nextID++
+		queue.songs = append(queue.songs, song)
+		restored++
+	}
+	queue.Unlock()
+
+	queue.requestPreload()
+
+	err = os.Remove(path)
+	if err != nil {
+		return restored, fmt.Errorf("RestoreQueue: %s", err)
+	}
+	return restored, nil
+}
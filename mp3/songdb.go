@@ -2,13 +2,14 @@ package mp3
 
 import (
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 	"regexp"
 	"sort"
 	"strings"
 	"sync"
+
+	"github.com/procrastimax/goparty/logging"
 )
 
 /**
@@ -31,7 +32,8 @@ func InitializeSongDBFromMemory(songDIr, downloadDir string) error {
 	ytDownloadDir = downloadDir
 	songdir = songDIr
 
-	songDB = make(map[string][]string)
+	//the database is built separately and replaced at the end, so the old database can be used while rescanning
+	db := make(map[string][]string)
 	songs := make([]string, 0)
 	//dirPath keeps track of the folder which is currently iterated, when it change, we start a new list (bc of new folder)
 	dirPath := ""
@@ -51,7 +53,7 @@ func InitializeSongDBFromMemory(songDIr, downloadDir string) error {
 				} else if dirPath != tempPath {
 					//if the dirPath differs from the current path, then we encountered a new directory
 					//we need to save all current traversed songs to the dirPath and set dirPath to the new path
-					songDB[dirPath] = songs
+					db[dirPath] = songs
 					songs = make([]string, 0)
 					dirPath = tempPath
 				}
@@ -64,16 +66,34 @@ func InitializeSongDBFromMemory(songDIr, downloadDir string) error {
 
 			return nil
 		})
+	if err == nil {
+		//if ended, then we need to add the last section
+		db[dirPath] = songs
+	}
+
+	mutex.Lock()
+	songDB = db
+	mutex.Unlock()
+
 	if err != nil {
 		return fmt.Errorf("ReadSongsFromMemory: %s", err)
 	}
 
-	//if ended, then we need to add the last section
-	songDB[dirPath] = songs
+	analyzeSongDBInBackground()
 
 	return nil
 }
 
+//RescanSongDB reads the song database from the song directories again, f.e. after songs were copied into the music folder
+//returns the count of songs in the database
+func RescanSongDB() (int, error) {
+	err := InitializeSongDBFromMemory(songdir, ytDownloadDir)
+	if err != nil {
+		return 0, fmt.Errorf("RescanSongDB: %s", err)
+	}
+	return len(getSongDBFiles()), nil
+}
+
 //AddSongToDB adds a song to the database with the given songname and songpath
 func AddSongToDB(songDir, songname string) {
 	if songDB == nil {
@@ -89,6 +109,8 @@ func AddSongToDB(songDir, songname string) {
 	}
 
 	mutex.Unlock()
+
+	analyzeInBackground(songDir + songname)
 }
 
 //CheckSongInDB returns a boolean indicating if the songname exists in the DB
@@ -103,16 +125,15 @@ func CheckSongInDB(songname string) bool {
 	return false
 }
 
-//CheckYTSongInDB checks whether or not a given song downloaded by youtubedl is in the map
-//it returns the matching filename if found
-func CheckYTSongInDB(ytURL string, downloadDir string) (string, error) {
+//GetYoutubeVideoID returns the video ID of a youtube link, short links (youtu.be) and normal links (?v=ID) are supported
+func GetYoutubeVideoID(ytURL string) (string, error) {
 	var videoIDStr string
 
 	//we got a youtube shortform url
 	isMatch, err := regexp.MatchString("https{0,1}://youtu\\.be/\\S*", ytURL)
 
 	if err != nil {
-		log.Fatalln("Regex for checking url against http://youtu.be/ link is invalid!")
+		logging.Fatal("Regex for checking url against http://youtu.be/ link is invalid", "err", err)
 	}
 
 	if isMatch {
@@ -139,6 +160,16 @@ func CheckYTSongInDB(ytURL string, downloadDir string) (string, error) {
 			videoIDStr = strArr[1]
 		}
 	}
+	return videoIDStr, nil
+}
+
+//CheckYTSongInDB checks whether or not a given song downloaded by youtubedl is in the map
+//it returns the matching filename if found
+func CheckYTSongInDB(ytURL string, downloadDir string) (string, error) {
+	videoIDStr, err := GetYoutubeVideoID(ytURL)
+	if err != nil {
+		return "", err
+	}
 
 	songs, ok := songDB[downloadDir]
 	if ok == false {
@@ -155,6 +186,19 @@ func CheckYTSongInDB(ytURL string, downloadDir string) (string, error) {
 	return "", nil
 }
 
+//getSongDBFiles returns the complete path of all songs in the songDB
+func getSongDBFiles() []string {
+	mutex.Lock()
+	defer mutex.Unlock()
+	files := make([]string, 0)
+	for dir, songs := range songDB {
+		for _, song := range songs {
+			files = append(files, dir+song)
+		}
+	}
+	return files
+}
+
 //GetSongDB returns the songDB map
 func GetSongDB() map[string][]string {
 	return songDB
@@ -196,15 +240,45 @@ func GetSortedSongList() []string {
 		songs = append(songs, strings.TrimPrefix(elem, songdir))
 
 		for _, songName := range songDB[elem] {
-			//check if the songs has the #____# mark
-			songName = strings.TrimSuffix(songName, ".mp3")
+			songs = append(songs, prettySongName(songName))
+		}
+	}
+	return songs
+}
 
-			if strings.Contains(songName, "#____#") {
-				songName = strings.Split(songName, "#____#")[0]
-			}
+//prettySongName removes the .mp3 suffix and the youtube id marked by #____# from a song filename
+func prettySongName(filename string) string {
+	songName := strings.TrimSuffix(filename, ".mp3")
 
-			songs = append(songs, songName)
+	if strings.Contains(songName, "#____#") {
+		songName = strings.Split(songName, "#____#")[0]
+	}
+	return songName
+}
+
+//GetSongDBEntries returns the songs of a songDB directory as playlist entries
+//the directory can also be given relative to the music path like in GetSortedSongList
+//when filter is not empty, only songs containing the filter are returned, an empty dir matches all directories
+func GetSongDBEntries(dir string, filter string) []PlaylistEntry {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	dirs := make([]string, 0)
+	for k := range songDB {
+		if len(dir) == 0 || k == dir || k == songdir+dir {
+			dirs = append(dirs, k)
 		}
 	}
-	return songs
+	sort.Strings(dirs)
+
+	entries := make([]PlaylistEntry, 0)
+	for _, k := range dirs {
+		for _, song := range songDB[k] {
+			if len(filter) > 0 && strings.Contains(strings.ToLower(song), strings.ToLower(filter)) == false {
+				continue
+			}
+			entries = append(entries, PlaylistEntry{Title: prettySongName(song), Path: k + song, Length: -1})
+		}
+	}
+	return entries
 }
@@ -0,0 +1,150 @@
+package mp3
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+
+	"github.com/faiface/beep/effects"
+
+	"github.com/procrastimax/goparty/logging"
+)
+
+const (
+	//MaxVolume is the volume in percent at which the music is played at full scale
+	MaxVolume = 100
+	//VolumeStep is the step in percent used when increasing or decreasing the volume
+	VolumeStep = 10
+)
+
+var (
+	//masterVolume wraps the music queue and is the streamer which is played by the speaker
+	masterVolume       = effects.Volume{Streamer: &queue, Base: 10}
+	volumePercent      = MaxVolume
+	muted              = false
+	playerSettingsPath string
+)
+
+//playerSettings are the player settings which are persisted across restarts
+type playerSettings struct {
+	Volume int  `json:"volume"`
+	Muted  bool `json:"muted"`
+}
+
+//InitPlayerSettings reads the persisted player settings from the given json file and applies them
+//when the file does not exist, the default settings are used
+func InitPlayerSettings(settingsPath string) error {
+	playerSettingsPath = settingsPath
+
+	file, err := ioutil.ReadFile(settingsPath)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("InitPlayerSettings: %s", err)
+	}
+
+	settings := playerSettings{Volume: MaxVolume}
+	err = json.Unmarshal(file, &settings)
+	if err != nil {
+		return fmt.Errorf("InitPlayerSettings: %s", err)
+	}
+
+	setVolume(settings.Volume)
+	setMute(settings.Muted)
+	return nil
+}
+
+//savePlayerSettings writes the current player settings to the settings file
+func savePlayerSettings() {
+	if len(playerSettingsPath) == 0 {
+		return
+	}
+
+	file, err := json.MarshalIndent(playerSettings{Volume: GetVolume(), Muted: IsMuted()}, "", " ")
+	if err != nil {
+		logging.Error("Could not save the player settings", "err", err)
+		return
+	}
+
+	err = ioutil.WriteFile(playerSettingsPath, file, 0644)
+	if err != nil {
+		logging.Error("Could not save the player settings", "err", err)
+	}
+}
+
+//setVolume sets the master volume without persisting it
+func setVolume(percent int) {
+	if percent < 0 {
+		percent = 0
+	} else if percent > MaxVolume {
+		percent = MaxVolume
+	}
+
+	outputMutex.Lock()
+	volumePercent = percent
+	masterVolume.Volume = VolumeToGain(percent)
+	//the gain is base^volume, so a volume of 0 would be the full loudness, the master volume needs to be silent instead
+	masterVolume.Silent = muted || percent == 0
+	outputMutex.Unlock()
+}
+
+//VolumeToGain returns the exponent to base 10 of the master volume gain for the given volume in percent
+//the amplitude is the squared volume fraction, which feels more natural than a linear scale, 0 percent is handled by silencing the master volume
+func VolumeToGain(percent int) float64 {
+	if percent <= 0 {
+		return 0
+	}
+	return 2 * math.Log10(float64(percent)/MaxVolume)
+}
+
+//setMute mutes or unmutes the master volume without persisting it
+func setMute(mute bool) {
+	outputMutex.Lock()
+	muted = mute
+	masterVolume.Silent = mute || volumePercent == 0
+	outputMutex.Unlock()
+}
+
+//SetVolume sets the master volume in percent (0-100)
+func SetVolume(percent int) {
+	setVolume(percent)
+	savePlayerSettings()
+	logging.Info("Volume set", "volume", GetVolume())
+}
+
+//ChangeVolume increases or decreases the master volume by the given percent
+func ChangeVolume(delta int) {
+	SetVolume(GetVolume() + delta)
+}
+
+//GetVolume returns the current master volume in percent
+func GetVolume() int {
+	outputMutex.Lock()
+	defer outputMutex.Unlock()
+	return volumePercent
+}
+
+//SetMute mutes or unmutes the music
+func SetMute(mute bool) {
+	setMute(mute)
+	savePlayerSettings()
+	if mute {
+		logging.Info("Speaker muted")
+	} else {
+		logging.Info("Speaker unmuted")
+	}
+}
+
+//ToggleMute mutes the music when it is unmuted and vice versa
+func ToggleMute() {
+	SetMute(!IsMuted())
+}
+
+//IsMuted returns true when the music is muted
+func IsMuted() bool {
+	outputMutex.Lock()
+	defer outputMutex.Unlock()
+	return muted
+}
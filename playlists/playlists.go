@@ -0,0 +1,150 @@
+//Package playlists stores named playlists (f.e. "warm-up" or "after midnight") which can be added to the queue in one go
+package playlists
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+/**
+	A playlist is a list of entries, every entry is either the path of a mp3 file or a youtube link.
+	All playlists are stored in a single json file, which is written on every change.
+**/
+
+var (
+	playlists     = make(map[string][]string)
+	playlistsPath string
+	mutex         sync.Mutex
+)
+
+//Init reads the stored playlists from the given json file, when the file does not exist, there are no playlists yet
+func Init(path string) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	playlistsPath = path
+	playlists = make(map[string][]string)
+
+	file, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("Init: %s", err)
+	}
+
+	err = json.Unmarshal(file, &playlists)
+	if err != nil {
+		return fmt.Errorf("Init: %s", err)
+	}
+	return nil
+}
+
+//save writes all playlists to the json file, the mutex must be held by the caller
+func save() error {
+	if len(playlistsPath) == 0 {
+		return nil
+	}
+
+	file, err := json.MarshalIndent(playlists, "", " ")
+	if err != nil {
+		return fmt.Errorf("save: %s", err)
+	}
+
+	err = ioutil.WriteFile(playlistsPath, file, 0644)
+	if err != nil {
+		return fmt.Errorf("save: %s", err)
+	}
+	return nil
+}
+
+//GetNames returns the names of all playlists sorted alphabetically
+func GetNames() []string {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	names := make([]string, 0, len(playlists))
+	for name := range playlists {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//Get returns the entries of the playlist with the given name, returns false if the playlist does not exist
+func Get(name string) ([]string, bool) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	entries, ok := playlists[name]
+	if ok == false {
+		return nil, false
+	}
+	return append([]string{}, entries...), true
+}
+
+//Save creates the playlist with the given name or replaces its entries, empty entries are removed
+func Save(name string, entries []string) error {
+	name = strings.TrimSpace(name)
+	if len(name) == 0 {
+		return fmt.Errorf("Save: the playlist needs a name")
+	}
+
+	cleaned := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if len(entry) > 0 {
+			cleaned = append(cleaned, entry)
+		}
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	playlists[name] = cleaned
+	return save()
+}
+
+//Add appends an entry to the playlist with the given name, the playlist is created when it does not exist
+func Add(name string, entry string) error {
+	entries, _ := Get(name)
+	return Save(name, append(entries, entry))
+}
+
+//Rename renames a playlist, fails when a playlist with the new name already exists
+func Rename(name string, newName string) error {
+	newName = strings.TrimSpace(newName)
+	if len(newName) == 0 {
+		return fmt.Errorf("Rename: the playlist needs a name")
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	entries, ok := playlists[name]
+	if ok == false {
+		return fmt.Errorf("Rename: playlist %s does not exist", name)
+	}
+	if _, ok := playlists[newName]; ok {
+		return fmt.Errorf("Rename: playlist %s already exists", newName)
+	}
+
+	delete(playlists, name)
+	playlists[newName] = entries
+	return save()
+}
+
+//Delete removes the playlist with the given name
+func Delete(name string) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if _, ok := playlists[name]; ok == false {
+		return fmt.Errorf("Delete: playlist %s does not exist", name)
+	}
+	delete(playlists, name)
+	return save()
+}
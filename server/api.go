@@ -0,0 +1,147 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/procrastimax/goparty/mp3"
+	"github.com/procrastimax/goparty/stats"
+)
+
+/**
+	JSON API for controlling the music from other programs
+	GET requests return the current state, POST requests change the state and are only allowed for admins
+**/
+
+type volumeAPI struct {
+	Volume int  `json:"volume"`
+	Muted  bool `json:"muted"`
+}
+
+type positionAPI struct {
+	Song     string  `json:"song"`
+	Playing  bool    `json:"playing"`
+	Position float64 `json:"position"`
+	Duration float64 `json:"duration"`
+}
+
+//writeJSON writes the given data as json response
+func writeJSON(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+//checkAPIRequest checks the method of an API request and whether the user is allowed to change something
+//returns false when the request was already answered with an error
+func checkAPIRequest(w http.ResponseWriter, r *http.Request) bool {
+	switch r.Method {
+	case "GET":
+		return true
+	case "POST":
+		if isAdmin(getRequestIP(r)) == false {
+			http.Error(w, "403 - Only the admin is allowed to do this", http.StatusForbidden)
+			return false
+		}
+		return true
+	default:
+		http.Error(w, "405 - Only GET and POST methods are supported", http.StatusMethodNotAllowed)
+		return false
+	}
+}
+
+//apiVolumeHandler returns the volume on GET requests
+//on POST requests the form values 'volume' (0-100) and 'muted' (true/false) can be used to change the volume
+func apiVolumeHandler(w http.ResponseWriter, r *http.Request) {
+	if checkAPIRequest(w, r) == false {
+		return
+	}
+
+	if r.Method == "POST" {
+		if value := r.FormValue("volume"); len(value) != 0 {
+			volume, err := strconv.Atoi(value)
+			if err != nil {
+				http.Error(w, "400 - volume needs to be a number between 0 and 100", http.StatusBadRequest)
+				return
+			}
+			mp3.SetVolume(volume)
+		}
+
+		if value := r.FormValue("muted"); len(value) != 0 {
+			muted, err := strconv.ParseBool(value)
+			if err != nil {
+				http.Error(w, "400 - muted needs to be true or false", http.StatusBadRequest)
+				return
+			}
+			mp3.SetMute(muted)
+		}
+	}
+
+	writeJSON(w, volumeAPI{Volume: mp3.GetVolume(), Muted: mp3.IsMuted()})
+}
+
+//apiPositionHandler returns the position and duration in seconds of the currently playing song on GET requests
+//on POST requests the form value 'position' ([+|-][m:]ss) can be used to seek in the song
+func apiPositionHandler(w http.ResponseWriter, r *http.Request) {
+	if checkAPIRequest(w, r) == false {
+		return
+	}
+
+	if r.Method == "POST" {
+		err := seekTo(r.FormValue("position"))
+		if err != nil {
+			http.Error(w, "400 - "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var data positionAPI
+	position, duration, ok := mp3.GetPosition()
+	if ok {
+		if songs := mp3.GetCurrentPlaylist(); len(songs) > 0 {
+			data.Song = songs[0].SongName
+		}
+		data.Playing = true
+		data.Position = position.Seconds()
+		data.Duration = duration.Seconds()
+	}
+	writeJSON(w, data)
+}
+
+//apiHistoryHandler returns the play history, the oldest song comes first
+//with the 'format' query value the history can also be returned as csv or m3u, only the admin is allowed to see it
+func apiHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "405 - Only GET methods are supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if isAdmin(getRequestIP(r)) == false {
+		http.Error(w, "403 - Only the admin is allowed to do this", http.StatusForbidden)
+		return
+	}
+
+	format := r.FormValue("format")
+	if len(format) == 0 {
+		format = "json"
+	}
+	writeHistoryExport(w, format, mp3.GetHistory(), false)
+}
+
+//apiStatsHandler returns the party statistics, only the admin is allowed to see them
+func apiStatsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "405 - Only GET methods are supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if isAdmin(getRequestIP(r)) == false {
+		http.Error(w, "403 - Only the admin is allowed to do this", http.StatusForbidden)
+		return
+	}
+
+	writeJSON(w, stats.GetSummary())
+}
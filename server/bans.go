@@ -0,0 +1,115 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/procrastimax/goparty/clients"
+	"github.com/procrastimax/goparty/logging"
+	"github.com/procrastimax/goparty/mp3"
+	"github.com/procrastimax/goparty/youtube"
+)
+
+const (
+	//localIP is the IP of the admin, the admin cannot be kicked or banned
+	localIP = "127.0.0.1"
+)
+
+//banMiddleware rejects all requests of banned users
+func banMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := getRequestIP(r)
+		if banned, until := clients.IsBanned(ip.String()); banned && ip.String() != localIP {
+			w.WriteHeader(http.StatusForbidden)
+			renderTemplate(w, "error", errorUI{ErrorMsg: "You are banned " + formatBanEnd(until) + "!"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+//setupKicking registers the queues from which the songs of kicked users are removed
+func setupKicking() {
+	clients.OnKick(youtube.RemoveUserDownloads)
+	clients.OnKick(mp3.RemoveUserSongs)
+}
+
+//formatBanEnd describes when a ban ends
+func formatBanEnd(until time.Time) string {
+	if until.IsZero() {
+		return "until the party is over"
+	}
+	return "until " + until.Format("15:04")
+}
+
+//kickUser removes all pending downloads and queued songs of the user
+func kickUser(ip string) (int, error) {
+	if ip == localIP {
+		return 0, fmt.Errorf("kickUser: the admin cannot be kicked")
+	}
+	removed := clients.Kick(ip)
+	logging.Info("Kicked user", "user", clients.GetUserNameToIP(ip), "ip", ip, "removed", removed)
+	return removed, nil
+}
+
+//banUser kicks the user and rejects all further requests of the user for the given duration, 0 bans until the program restarts
+func banUser(ip string, duration time.Duration) error {
+	if ip == localIP {
+		return fmt.Errorf("banUser: the admin cannot be banned")
+	}
+	clients.Ban(ip, duration)
+	_, err := kickUser(ip)
+	if err != nil {
+		return fmt.Errorf("banUser: %s", err)
+	}
+	_, until := clients.IsBanned(ip)
+	logging.Info("Banned user", "user", clients.GetUserNameToIP(ip), "ip", ip, "until", formatBanEnd(until))
+	return nil
+}
+
+//usersHandler kicks, bans and unbans users, only the admin can do this
+func usersHandler(w http.ResponseWriter, r *http.Request) {
+	if isAdmin(getRequestIP(r)) == false {
+		renderTemplate(w, "error", errorUI{ErrorMsg: "Only the admin can kick or ban users!"})
+		return
+	}
+	if r.Method != "POST" {
+		http.Error(w, "405 - Only POST methods are supported for /users", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ip := r.FormValue("ip")
+	if len(ip) == 0 {
+		renderTemplate(w, "error", errorUI{ErrorMsg: "No user was selected!"})
+		return
+	}
+
+	var err error
+	switch r.FormValue("action") {
+	case "kick":
+		_, err = kickUser(ip)
+	case "ban":
+		minutes := 0
+		if value := r.FormValue("minutes"); len(value) > 0 {
+			minutes, err = strconv.Atoi(value)
+			if err != nil || minutes < 0 {
+				renderTemplate(w, "error", errorUI{ErrorMsg: "The ban duration needs to be a positive number of minutes!"})
+				return
+			}
+		}
+		err = banUser(ip, time.Duration(minutes)*time.Minute)
+	case "unban":
+		clients.Unban(ip)
+	default:
+		renderTemplate(w, "error", errorUI{ErrorMsg: "Unknown action, use kick, ban or unban!"})
+		return
+	}
+
+	if err != nil {
+		renderTemplate(w, "error", errorUI{ErrorMsg: err.Error()})
+		return
+	}
+	http.Redirect(w, r, "/", http.StatusFound)
+}
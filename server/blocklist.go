@@ -0,0 +1,76 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/procrastimax/goparty/blocklist"
+	"github.com/procrastimax/goparty/logging"
+	"github.com/procrastimax/goparty/mp3"
+)
+
+type blocklistUI struct {
+	List blocklist.Blocklist
+}
+
+//Text returns the entries as text with one entry per line
+func (ui blocklistUI) Text(entries []string) string {
+	return strings.Join(entries, "\n")
+}
+
+//blocklistHandler shows the blocklist on GET requests and replaces it on POST requests
+func blocklistHandler(w http.ResponseWriter, r *http.Request) {
+	if isAdmin(getRequestIP(r)) == false {
+		renderTemplate(w, "error", errorUI{ErrorMsg: "Only the admin can edit the blocklist!"})
+		return
+	}
+
+	if r.Method == "GET" {
+		renderTemplate(w, "blocklist", blocklistUI{blocklist.Get()})
+		return
+	} else if r.Method != "POST" {
+		http.Error(w, "405 - Only GET and POST methods are supported for /blocklist", http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := blocklist.Set(blocklist.Blocklist{
+		VideoIDs:  strings.Split(r.FormValue("videoIDs"), "\n"),
+		Channels:  strings.Split(r.FormValue("channels"), "\n"),
+		Titles:    strings.Split(r.FormValue("titles"), "\n"),
+		FilePaths: strings.Split(r.FormValue("filePaths"), "\n"),
+	})
+	if err != nil {
+		renderTemplate(w, "error", errorUI{ErrorMsg: "Could not save the blocklist: " + err.Error()})
+		return
+	}
+	http.Redirect(w, r, "/blocklist", http.StatusFound)
+}
+
+//blockCurrentSong adds the currently playing song to the blocklist and skips it
+//downloaded songs are blocked by their youtube video ID, offline songs by their file path
+func blockCurrentSong() error {
+	songs := mp3.GetCurrentPlaylist()
+	if len(songs) == 0 {
+		return fmt.Errorf("blockCurrentSong: no song is playing")
+	}
+	song := songs[0]
+
+	list := blocklist.Get()
+	filename := strings.TrimSuffix(filepath.Base(song.FilePath), ".mp3")
+	if split := strings.Split(filename, "#____#"); len(split) == 2 {
+		list.VideoIDs = append(list.VideoIDs, split[1])
+	} else {
+		list.FilePaths = append(list.FilePaths, song.FilePath)
+	}
+
+	err := blocklist.Set(list)
+	if err != nil {
+		return fmt.Errorf("blockCurrentSong: %s", err)
+	}
+
+	logging.Info("Blocked song", "song", song.SongName)
+	mp3.SkipSong()
+	return nil
+}
@@ -4,9 +4,24 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/procrastimax/goparty/logging"
+	"github.com/procrastimax/goparty/mp3"
+)
+
+const (
+	//defaultLogFileName is the log file next to the config which is used with the console when no logFile is set
+	defaultLogFileName = "goparty.log"
+)
+
+var (
+	//configMutex guards the running config, which is replaced by a reload while the handlers read it
+	configMutex sync.RWMutex
 )
 
 //Config handles multiple settings used for the logic/ handling of the music queue
@@ -22,6 +37,118 @@ type Config struct {
 
 	//AllUserAdmin when set to true, then all user receive the admin user interface and can stop/skip music
 	AllUserAdmin bool `json:"allUserAdmin"`
+
+	//NormalizeLoudness when set to true, then the loudness of all songs gets analyzed and adjusted to the same level
+	NormalizeLoudness bool `json:"normalizeLoudness"`
+
+	//Output specifies where the music is played: "speaker" (default), "null" (no sound card needed), "wav" (records into RecordPath) or "icecast" (sends to the icecast mount)
+	Output string `json:"output"`
+
+	//RecordPath specifies the wav file the music is recorded to when using the "wav" output
+	RecordPath string `json:"recordPath"`
+
+	//IcecastHost, IcecastPort, IcecastMount and IcecastPassword specify the icecast mount used by the "icecast" output
+	IcecastHost     string `json:"icecastHost"`
+	IcecastPort     int    `json:"icecastPort"`
+	IcecastMount    string `json:"icecastMount"`
+	IcecastPassword string `json:"icecastPassword"`
+
+	//MaxStreamListeners specifies how many users can listen to the music stream at /stream.wav at the same time, 0 disables the stream
+	MaxStreamListeners int `json:"maxStreamListeners"`
+
+	//Autoplay specifies which songs are played when the queue runs empty: "off" (default), "random" (offline song database), "directory" (AutoplayPath), "history" (play history) or "playlist" (m3u file at AutoplayPath)
+	Autoplay string `json:"autoplay"`
+
+	//AutoplayPath specifies the directory or the m3u playlist used by the "directory" and "playlist" autoplay
+	AutoplayPath string `json:"autoplayPath"`
+
+	//MaxPendingSongs specifies how many songs a user can have in the queue and the download queue at the same time
+	MaxPendingSongs int `json:"maxPendingSongs"`
+
+	//MaxSubmissions specifies how many songs a user can add within SubmissionWindow minutes
+	MaxSubmissions   int `json:"maxSubmissions"`
+	SubmissionWindow int `json:"submissionWindowMinutes"`
+
+	//MaxSongDuration specifies how many minutes a song added by a user can be long
+	MaxSongDuration int `json:"maxSongMinutes"`
+
+	//TrimLongSongs when set to true, then youtube videos longer than MaxSongDuration are cut instead of being rejected
+	TrimLongSongs bool `json:"trimLongSongs"`
+
+	//AllowAgeRestricted when set to true, then users can add age-restricted youtube videos
+	AllowAgeRestricted bool `json:"allowAgeRestricted"`
+
+	//MaxQueueLength specifies how many songs can be in the queue and the download queue together before users cannot add more songs
+	MaxQueueLength int `json:"maxQueueLength"`
+
+	//DuplicateCooldown specifies how many minutes after a song was played it cannot be added again
+	DuplicateCooldown int `json:"duplicateCooldownMinutes"`
+
+	//DuplicateTitles when set to true, then songs with the same title are treated as the same song, even when they are different files
+	DuplicateTitles bool `json:"duplicateTitles"`
+
+	//LogLevel specifies the lowest level of the logged messages: "debug", "info" (default), "warn" or "error"
+	LogLevel string `json:"logLevel"`
+
+	//LogFormat specifies the format of the log: "text" (default) or "json"
+	LogFormat string `json:"logFormat"`
+
+	//LogFile specifies the file the log is written to, without it the log is written to goparty.log next to the config when the console runs in a terminal and to stderr otherwise
+	LogFile string `json:"logFile"`
+
+	//LogMaxSize specifies the size in megabytes at which the log file is rotated, 0 disables the rotation
+	LogMaxSize int `json:"logMaxSizeMB"`
+
+	//LogMaxFiles specifies how many rotated log files are kept
+	LogMaxFiles int `json:"logMaxFiles"`
+}
+
+//getConfig returns the running config, the returned config is never changed, a reload replaces it with a new one
+func getConfig() *Config {
+	configMutex.RLock()
+	defer configMutex.RUnlock()
+	return config
+}
+
+//setConfig replaces the running config
+func setConfig(cfg *Config) {
+	configMutex.Lock()
+	config = cfg
+	configMutex.Unlock()
+}
+
+//defaultConfig returns the config with the default values, the paths are not set
+func defaultConfig() Config {
+	return Config{
+		DownloadPath:            "",
+		MusicPath:               "",
+		AllUserAdmin:            false,
+		UpvotesNeededForRanking: 2,
+		NormalizeLoudness:       true,
+		Output:                  "speaker",
+		RecordPath:              "",
+		IcecastHost:             "localhost",
+		IcecastPort:             8000,
+		IcecastMount:            "/goparty.mp3",
+		IcecastPassword:         "",
+		MaxStreamListeners:      5,
+		Autoplay:                "off",
+		AutoplayPath:            "",
+		MaxPendingSongs:         5,
+		MaxSubmissions:          10,
+		SubmissionWindow:        30,
+		MaxSongDuration:         10,
+		TrimLongSongs:           false,
+		AllowAgeRestricted:      false,
+		MaxQueueLength:          100,
+		DuplicateCooldown:       30,
+		DuplicateTitles:         false,
+		LogLevel:                "info",
+		LogFormat:               "text",
+		LogFile:                 "",
+		LogMaxSize:              10,
+		LogMaxFiles:             3,
+	}
 }
 
 //CreateInitialConfig creates the initial config if it wasn't created before.
@@ -30,14 +157,7 @@ func CreateInitialConfig(configPath string) error {
 		fileName := strings.Split(configPath, string(os.PathSeparator))
 		err := os.MkdirAll(strings.TrimSuffix(configPath, fileName[len(fileName)-1]), 0700)
 
-		config := Config{
-			DownloadPath:            "",
-			MusicPath:               "",
-			AllUserAdmin:            false,
-			UpvotesNeededForRanking: 2,
-		}
-
-		file, err := json.MarshalIndent(config, "", " ")
+		file, err := json.MarshalIndent(defaultConfig(), "", " ")
 		if err != nil {
 			return fmt.Errorf("CreateInitialConfig: %s", err)
 		}
@@ -59,6 +179,11 @@ func checkConfigExists(configPath string) bool {
 
 //ReadConfig reads the current config and returns the config struct and a potential error
 func ReadConfig(configPath string) (*Config, error) {
+	return readConfig(configPath, nil)
+}
+
+//readConfig reads the config and replaces the values given by their json name in the overrides
+func readConfig(configPath string, overrides map[string]string) (*Config, error) {
 	if checkConfigExists(configPath) == false {
 		//when file does not exist, create it first
 		CreateInitialConfig(configPath)
@@ -78,15 +203,190 @@ func ReadConfig(configPath string) (*Config, error) {
 		return nil, fmt.Errorf("ReadConfig: %s", err)
 	}
 
+	err = applyOverrides(config, overrides)
+	if err != nil {
+		return nil, fmt.Errorf("ReadConfig: %s", err)
+	}
+
 	if len(config.DownloadPath) == 0 {
-		log.Printf("\n\n-->It seems that you haven't already set a path for specifing in which folder the youtube songs should be downloaded!\nPlease set one under %s\n\n", configPath)
-		return nil, fmt.Errorf("ReadConfig: downloadpath not set")
+		return nil, fmt.Errorf("ReadConfig: downloadPath not set, please set the folder the youtube songs are downloaded to in %s", configPath)
 	}
 
 	if len(config.MusicPath) == 0 {
-		log.Printf("\n\n-->It seems that you haven't already set a path for specifing which folder should be used to provide an offline song collection. Please set it at least to the same folder as the download directory! You can set this under: %s\n\n", configPath)
-		return nil, fmt.Errorf("ReadConfig: path for music not set")
+		return nil, fmt.Errorf("ReadConfig: musicPath not set, please set the folder of the offline song collection in %s, at least to the same folder as the download directory", configPath)
 	}
 
+	config.DownloadPath = normalizeDirPath(config.DownloadPath)
+	config.MusicPath = normalizeDirPath(config.MusicPath)
 	return config, nil
 }
+
+//normalizeDirPath cleans the path of a folder, replaces a leading ~ with the home directory and adds the trailing separator
+//so the paths in the config work with or without trailing slash and with forward slashes on windows
+func normalizeDirPath(path string) string {
+	if len(path) == 0 {
+		return path
+	}
+
+	path = filepath.FromSlash(path)
+	if path == "~" || strings.HasPrefix(path, "~"+string(os.PathSeparator)) {
+		if homedir, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(homedir, path[1:])
+		}
+	}
+
+	path = filepath.Clean(path)
+	if strings.HasSuffix(path, string(os.PathSeparator)) {
+		return path
+	}
+	return path + string(os.PathSeparator)
+}
+
+//checkDir returns an error when the path is not an existing folder, writable folders are checked by creating a file in them
+func checkDir(path string, writable bool) error {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("the folder %s does not exist, please create it", path)
+	} else if err != nil {
+		return err
+	} else if info.IsDir() == false {
+		return fmt.Errorf("%s is not a folder", path)
+	}
+
+	if writable {
+		file, err := ioutil.TempFile(path, ".goparty-")
+		if err != nil {
+			return fmt.Errorf("the folder %s is not writable", path)
+		}
+		file.Close()
+		os.Remove(file.Name())
+	}
+	return nil
+}
+
+//validateConfig checks the values of the config which cannot be checked while reading the json
+//all problems of the config are returned together in one error
+func validateConfig(cfg *Config) error {
+	problems := make([]string, 0)
+
+	if err := checkDir(cfg.MusicPath, false); err != nil {
+		problems = append(problems, "musicPath: "+err.Error())
+	}
+	//the download folder is created like in the setup wizard, only the music folder has to exist
+	if err := os.MkdirAll(cfg.DownloadPath, 0755); err != nil {
+		problems = append(problems, fmt.Sprintf("downloadPath: the folder %s cannot be created: %s", cfg.DownloadPath, err))
+	} else if err := checkDir(cfg.DownloadPath, true); err != nil {
+		problems = append(problems, "downloadPath: "+err.Error())
+	}
+
+	counts := map[string]int{
+		"maxStreamListeners":       cfg.MaxStreamListeners,
+		"maxPendingSongs":          cfg.MaxPendingSongs,
+		"maxSubmissions":           cfg.MaxSubmissions,
+		"submissionWindowMinutes":  cfg.SubmissionWindow,
+		"maxSongMinutes":           cfg.MaxSongDuration,
+		"maxQueueLength":           cfg.MaxQueueLength,
+		"duplicateCooldownMinutes": cfg.DuplicateCooldown,
+		"logMaxSizeMB":             cfg.LogMaxSize,
+		"logMaxFiles":              cfg.LogMaxFiles,
+	}
+	for name, value := range counts {
+		if value < 0 {
+			problems = append(problems, fmt.Sprintf("%s cannot be negative, 0 disables it", name))
+		}
+	}
+	if cfg.UpvotesNeededForRanking < 1 {
+		problems = append(problems, "upvotesForRerank needs to be at least 1")
+	}
+	if cfg.MaxSubmissions > 0 && cfg.SubmissionWindow == 0 {
+		problems = append(problems, "submissionWindowMinutes needs to be set when maxSubmissions is set")
+	}
+	//the icecast settings are only used by the icecast output
+	if cfg.Output == "icecast" {
+		if len(cfg.IcecastHost) == 0 || len(cfg.IcecastMount) == 0 || len(cfg.IcecastPassword) == 0 {
+			problems = append(problems, "the icecast output needs an icecastHost, icecastMount and icecastPassword")
+		}
+		if cfg.IcecastPort < 1 || cfg.IcecastPort > 65535 {
+			problems = append(problems, "icecastPort needs to be between 1 and 65535")
+		}
+	}
+
+	if _, err := createOutput(cfg); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	switch mp3.AutoplayMode(strings.ToLower(cfg.Autoplay)) {
+	case "", mp3.AutoplayOff, mp3.AutoplayRandom, mp3.AutoplayHistory:
+	case mp3.AutoplayDirectory, mp3.AutoplayPlaylist:
+		if _, err := os.Stat(cfg.AutoplayPath); err != nil {
+			problems = append(problems, fmt.Sprintf("autoplayPath needs to be an existing folder or m3u file for the autoplay mode %s", cfg.Autoplay))
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("unknown autoplay mode %s, use off, random, directory, history or playlist", cfg.Autoplay))
+	}
+
+	if _, err := logging.ParseLevel(cfg.LogLevel); err != nil {
+		problems = append(problems, "logLevel: "+err.Error())
+	}
+	switch strings.ToLower(cfg.LogFormat) {
+	case "", "text", "json":
+	default:
+		problems = append(problems, fmt.Sprintf("unknown logFormat %s, use text or json", cfg.LogFormat))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("validateConfig: the config is invalid:\n - %s", strings.Join(problems, "\n - "))
+	}
+	return nil
+}
+
+//getConfigWarnings returns the settings of a valid config which probably do not work as intended
+func getConfigWarnings(cfg *Config) []string {
+	warnings := make([]string, 0)
+	if strings.HasPrefix(cfg.DownloadPath, cfg.MusicPath) == false {
+		warnings = append(warnings, "the downloadPath is not inside the musicPath, downloaded songs are not in the song database after a restart")
+	}
+	if cfg.TrimLongSongs && cfg.MaxSongDuration == 0 {
+		warnings = append(warnings, "trimLongSongs has no effect without maxSongMinutes")
+	}
+	if cfg.AllUserAdmin {
+		warnings = append(warnings, "allUserAdmin is set, every guest can control the music")
+	}
+	return warnings
+}
+
+//applyConfig passes the settings of the config which can be changed while the program runs to the music queue and the download worker
+func applyConfig() {
+	cfg := getConfig()
+	mp3.SetLoudnessNormalization(cfg.NormalizeLoudness)
+	mp3.SetNeededUpvoteCount(cfg.UpvotesNeededForRanking)
+	mp3.SetMaxStreamListeners(cfg.MaxStreamListeners)
+	setDownloadLimits()
+	mp3.SetDuplicateDetection(time.Duration(cfg.DuplicateCooldown)*time.Minute, cfg.DuplicateTitles)
+
+	err := mp3.SetAutoplay(cfg.Autoplay, cfg.AutoplayPath)
+	if err != nil {
+		logging.Error("Could not set the autoplay", "err", err)
+	}
+
+	err = logging.SetLevel(cfg.LogLevel)
+	if err != nil {
+		logging.Error("Could not set the log level", "err", err)
+	}
+}
+
+//getLogSettings returns the settings of the log from the config
+//when the console runs in a terminal and no log file is set, the log is written to a file next to the config, so it does not get mixed with the console prompt
+func getLogSettings(cfg *Config, console bool) logging.Settings {
+	file := cfg.LogFile
+	if len(file) == 0 && console {
+		file = filepath.Join(filepath.Dir(configPath), defaultLogFileName)
+	}
+	return logging.Settings{
+		Level:     cfg.LogLevel,
+		Format:    cfg.LogFormat,
+		File:      file,
+		MaxSizeMB: cfg.LogMaxSize,
+		MaxFiles:  cfg.LogMaxFiles,
+	}
+}
@@ -0,0 +1,441 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/procrastimax/goparty/clients"
+	"github.com/procrastimax/goparty/logging"
+	"github.com/procrastimax/goparty/mp3"
+	"github.com/procrastimax/goparty/playlists"
+	"github.com/procrastimax/goparty/youtube"
+)
+
+const (
+	//watchInterval is the default refresh interval of the live view of the watch command
+	watchInterval = 2 * time.Second
+)
+
+func createWelcomeMessage(ip string) string {
+	builder := strings.Builder{}
+	builder.WriteString("\n-------------------------------------\n")
+	builder.WriteString("GOPARTY - The Youtube Music Queue\n")
+	builder.WriteString("-------------------------------------\n\n")
+	builder.WriteString("Hello you are the admin!\n")
+	builder.WriteString("\033[0;31mYour local IP is: ")
+	builder.WriteString(ip + "\033[0m\n")
+	builder.WriteString("The config can be found under: ")
+	builder.WriteString(configPath + "\n")
+	builder.WriteString("Changes of the config file are applied automatically, a changed download path or output needs a restart of the program!")
+	builder.WriteString("\n\n")
+	builder.WriteString("Please open your webbrowser on this machine and enter: 'localhost:" + getListenPort() + "' for viewing the admin page!\n")
+	builder.WriteString("All other users can view the website under: 'IP:" + getListenPort() + "'. The IP is written above.\n\n")
+	builder.WriteString("You can enter the following commands:\n")
+	builder.WriteString("- help (shows this text)\n")
+	builder.WriteString("- play (starts stopped or paused music)\n")
+	builder.WriteString("- pause (pauses the music)\n")
+	builder.WriteString("- stop [clear] (stops the music and closes the speaker, clear also removes all songs from the queue)\n")
+	builder.WriteString("- status (shows whether the music is playing, paused or stopped, the volume and the position in the current song)\n")
+	builder.WriteString("- skip (skips the current playing song)\n")
+	builder.WriteString("- list/queue (lists all current songs in the playing queue with their position)\n")
+	builder.WriteString("- remove <pos> (removes the song at the position from the queue, 0 skips the current song)\n")
+	builder.WriteString("- move <pos> <new pos> (moves the song at the position to the new position)\n")
+	builder.WriteString("- pin/unpin <pos> (pins the song behind the current song, so it is played next and no other song passes it)\n")
+	builder.WriteString("- downloads (lists the videos which are downloading or waiting to be downloaded)\n")
+	builder.WriteString("- history [count] (lists the last played songs, default 10)\n")
+	builder.WriteString("- volume [0-100] (shows or sets the volume)\n")
+	builder.WriteString("- mute/unmute (mutes or unmutes the music)\n")
+	builder.WriteString("- pos (shows the position in the current song)\n")
+	builder.WriteString("- seek [+|-][m:]ss (jumps to a position, or relative to the current position with +/-)\n")
+	builder.WriteString("- import <file> [user IP] (adds the songs of a m3u/m3u8/pls playlist which are in the song database to the queue)\n")
+	builder.WriteString("- export <file> (saves the current queue as m3u playlist)\n")
+	builder.WriteString("- block (skips the current song and adds it to the blocklist)\n")
+	builder.WriteString("- playlists (lists all saved playlists)\n")
+	builder.WriteString("- playlist <name> (adds all songs of the saved playlist to the queue)\n")
+	builder.WriteString("- users (lists all users with their IP, their queued songs and downloads and whether they are banned)\n")
+	builder.WriteString("- kick <IP> (removes all queued songs and pending downloads of the user)\n")
+	builder.WriteString("- ban <IP> [minutes] (kicks the user and blocks the website for the user, without minutes until the program restarts)\n")
+	builder.WriteString("- unban <IP> (lifts the ban of the user)\n")
+	builder.WriteString("- autoplay [off|random|directory|history|playlist] [path] (shows or sets where songs are taken from when the queue runs empty)\n")
+	builder.WriteString("- rescan (reads the song database from the music folder again)\n")
+	builder.WriteString("- reload (reads the config file again, the same happens when the file changes or on SIGHUP)\n")
+	builder.WriteString("- watch [seconds] (shows the player, the queue and the downloads and refreshes them until enter is pressed)\n")
+	builder.WriteString("- exit/quit (quits the program, the queue is restored on the next start)\n")
+	return builder.String()
+}
+
+func handleUserInput() error {
+	scanner := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Print("> ")
+		ok := scanner.Scan()
+		args := strings.Fields(scanner.Text())
+		if len(args) == 0 {
+			args = []string{""}
+		}
+		switch args[0] {
+		case "play":
+			err := mp3.Start()
+			if err != nil {
+				fmt.Println(err)
+			}
+		case "pause":
+			err := mp3.Pause()
+			if err != nil {
+				fmt.Println(err)
+			}
+		case "stop":
+			mp3.Stop(len(args) > 1 && args[1] == "clear")
+		case "status":
+			printStatus()
+		case "skip":
+			mp3.SkipSong()
+		case "list", "queue":
+			fmt.Println()
+			printQueue()
+			fmt.Println()
+		case "remove", "rm":
+			if len(args) < 2 {
+				fmt.Println("usage: remove <pos>")
+				break
+			}
+			songID, err := strconv.Atoi(args[1])
+			if err == nil {
+				err = mp3.RemoveSong(songID)
+			}
+			if err != nil {
+				fmt.Println(err)
+			}
+		case "move", "mv":
+			if len(args) < 3 {
+				fmt.Println("usage: move <pos> <new pos>")
+				break
+			}
+			songID, err := strconv.Atoi(args[1])
+			if err != nil {
+				fmt.Println(err)
+				break
+			}
+			position, err := strconv.Atoi(args[2])
+			if err == nil {
+				err = mp3.MoveSong(songID, position)
+			}
+			if err != nil {
+				fmt.Println(err)
+			}
+		case "pin", "unpin":
+			if len(args) < 2 {
+				fmt.Printf("usage: %s <pos>\n", args[0])
+				break
+			}
+			songID, err := strconv.Atoi(args[1])
+			if err == nil {
+				err = mp3.PinSong(songID, args[0] == "pin")
+			}
+			if err != nil {
+				fmt.Println(err)
+			}
+		case "downloads":
+			fmt.Println()
+			printDownloads()
+			fmt.Println()
+		case "history":
+			count := 10
+			if len(args) > 1 {
+				n, err := strconv.Atoi(args[1])
+				if err != nil || n <= 0 {
+					fmt.Println("usage: history [count], the count needs to be a number greater than 0!")
+					break
+				}
+				count = n
+			}
+			entries := mp3.GetHistory()
+			if count > 0 && count < len(entries) {
+				entries = entries[len(entries)-count:]
+			}
+			fmt.Println()
+			for _, entry := range entries {
+				skipped := ""
+				if entry.Skipped {
+					skipped = " (skipped)"
+				}
+				fmt.Println(" - " + entry.Start.Format("15:04") + " " + entry.SongName + skipped + "\tby: " + entry.UserName + " (" + entry.UserIP + ")\tupvotes: " + strconv.Itoa(entry.Upvotes))
+			}
+			fmt.Println()
+		case "volume", "vol":
+			if len(args) > 1 {
+				volume, err := strconv.Atoi(args[1])
+				if err != nil {
+					fmt.Println("volume needs to be a number between 0 and 100!")
+					break
+				}
+				mp3.SetVolume(volume)
+			} else {
+				fmt.Printf("Volume: %d%% muted: %t\n", mp3.GetVolume(), mp3.IsMuted())
+			}
+		case "pos":
+			if position, duration, ok := mp3.GetPosition(); ok {
+				fmt.Printf("%s / %s\n", formatDuration(position), formatDuration(duration))
+			} else {
+				fmt.Println("No song is playing!")
+			}
+		case "seek":
+			if len(args) < 2 {
+				fmt.Println("usage: seek [+|-][m:]ss")
+				break
+			}
+			err := seekTo(args[1])
+			if err != nil {
+				fmt.Println(err)
+			}
+		case "import":
+			if len(args) < 2 {
+				fmt.Println("usage: import <playlist file> [user IP]")
+				break
+			}
+			err := importPlaylistFile(args[1], args[2:])
+			if err != nil {
+				fmt.Println(err)
+			}
+		case "export":
+			if len(args) < 2 {
+				fmt.Println("usage: export <m3u file>")
+				break
+			}
+			err := exportQueueFile(args[1])
+			if err != nil {
+				fmt.Println(err)
+			}
+		case "block":
+			err := blockCurrentSong()
+			if err != nil {
+				fmt.Println(err)
+			}
+		case "playlists":
+			for _, name := range playlists.GetNames() {
+				entries, _ := playlists.Get(name)
+				fmt.Printf(" - %s (%d songs)\n", name, len(entries))
+			}
+		case "playlist":
+			if len(args) < 2 {
+				fmt.Println("usage: playlist <name>")
+				break
+			}
+			_, missing, err := enqueuePlaylist(strings.Join(args[1:], " "), "127.0.0.1")
+			if err != nil {
+				fmt.Println(err)
+			}
+			for _, entry := range missing {
+				fmt.Println(" - not in song database: " + entry)
+			}
+		case "users":
+			printUsers()
+		case "kick":
+			if len(args) < 2 {
+				fmt.Println("usage: kick <IP>")
+				break
+			}
+			_, err := kickUser(args[1])
+			if err != nil {
+				fmt.Println(err)
+			}
+		case "ban":
+			if len(args) < 2 {
+				fmt.Println("usage: ban <IP> [minutes]")
+				break
+			}
+			minutes := 0
+			if len(args) > 2 {
+				var err error
+				minutes, err = strconv.Atoi(args[2])
+				if err != nil || minutes < 0 {
+					fmt.Println("the ban duration needs to be a positive number of minutes!")
+					break
+				}
+			}
+			err := banUser(args[1], time.Duration(minutes)*time.Minute)
+			if err != nil {
+				fmt.Println(err)
+			}
+		case "unban":
+			if len(args) < 2 {
+				fmt.Println("usage: unban <IP>")
+				break
+			}
+			if clients.Unban(args[1]) == false {
+				fmt.Println(args[1] + " is not banned!")
+			}
+		case "autoplay":
+			if len(args) > 1 {
+				path := ""
+				if len(args) > 2 {
+					path = strings.Join(args[2:], " ")
+				}
+				err := mp3.SetAutoplay(args[1], path)
+				if err != nil {
+					fmt.Println(err)
+					break
+				}
+			}
+			mode, path := mp3.GetAutoplay()
+			fmt.Printf("Autoplay: %s %s\n", mode, path)
+		case "rescan":
+			count, err := mp3.RescanSongDB()
+			if err != nil {
+				fmt.Println(err)
+				break
+			}
+			fmt.Printf("Song database contains %d songs\n", count)
+		case "reload":
+			err := reloadConfig()
+			if err != nil {
+				fmt.Println(err)
+			}
+		case "watch":
+			interval := watchInterval
+			if len(args) > 1 {
+				seconds, err := strconv.Atoi(args[1])
+				if err != nil || seconds <= 0 {
+					fmt.Println("the refresh interval needs to be a positive number of seconds!")
+					break
+				}
+				interval = time.Duration(seconds) * time.Second
+			}
+			ok = watch(scanner, interval)
+		case "mute":
+			mp3.SetMute(true)
+		case "unmute":
+			mp3.SetMute(false)
+		case "help":
+			fmt.Println(createWelcomeMessage(serverIP))
+		case "exit", "quit", "q":
+			requestShutdown()
+			return nil
+		default:
+			if len(scanner.Text()) > 0 {
+				fmt.Println("unknown command!")
+			}
+		}
+
+		if scanner.Err() != nil {
+			return fmt.Errorf("handleUserInput: %s", scanner.Err().Error())
+		}
+
+		if ok == false {
+			logging.Info("Stopped listening to the console input")
+			break
+		}
+	}
+	return nil
+}
+
+//printStatus prints the state of the player, the volume and the position in the current song
+func printStatus() {
+	fmt.Printf("Player is %s\tvolume: %d%%", mp3.GetPlayerState(), mp3.GetVolume())
+	if mp3.IsMuted() {
+		fmt.Print(" (muted)")
+	}
+	if err := mp3.GetOutputError(); err != nil {
+		fmt.Printf("\toutput failed: %s", err)
+	}
+	if position, duration, ok := mp3.GetPosition(); ok {
+		fmt.Printf("\tposition: %s / %s", formatDuration(position), formatDuration(duration))
+	}
+	fmt.Println()
+}
+
+//printQueue prints all songs of the queue with their position, which is used by the remove, move and pin commands
+func printQueue() {
+	songs := mp3.GetCurrentPlaylist()
+	if len(songs) == 0 {
+		fmt.Println("The queue is empty")
+		return
+	}
+	for i, song := range songs {
+		state := ""
+		if i == 0 {
+			state = " (playing)"
+		} else if song.Pinned {
+			state = " (pinned)"
+		}
+		fmt.Printf(" %2d  %s%s\tby: %s (%s)\tupvotes: %d\n", i, song.SongName, state, song.UserName, song.UserIP, song.GetUpvotesCount())
+	}
+}
+
+//printDownloads prints all videos which are downloading or waiting to be downloaded
+func printDownloads() {
+	downloads := youtube.GetPendingDownloads()
+	if len(downloads) == 0 {
+		fmt.Println("No videos are waiting for download")
+		return
+	}
+	for _, download := range downloads {
+		title := download.URL
+		if len(download.Title) > 0 {
+			title = download.Title + " [" + formatDuration(download.Duration) + "]"
+		}
+		if download.Downloading {
+			title += " (downloading)"
+		}
+		fmt.Println(" - " + title + "\tby: " + download.UserName + " (" + download.UserIP + ")")
+	}
+}
+
+//printUsers prints all users with their count of queued songs and downloads and whether they are banned
+func printUsers() {
+	bans := clients.GetBans()
+	users := clients.GetUserProperties()
+	if len(users) == 0 && len(bans) == 0 {
+		fmt.Println("No user added a song yet")
+		return
+	}
+	for ip, user := range users {
+		banned := ""
+		if until, ok := bans[ip]; ok {
+			banned = "\tbanned " + formatBanEnd(until)
+		}
+		fmt.Printf(" - %s (%s)\tqueued: %d\tdownloading: %d%s\n", user.UserName, ip, user.PlaylistSongs, user.DownloadingSongs, banned)
+	}
+	for ip, until := range bans {
+		if _, ok := users[ip]; ok == false {
+			fmt.Println(" - " + ip + "\tbanned " + formatBanEnd(until))
+		}
+	}
+}
+
+//watch clears the console and shows the player, the queue and the downloads, they are refreshed in the given interval until enter is pressed
+//returns false when the input was closed
+func watch(scanner *bufio.Scanner, interval time.Duration) bool {
+	stop := make(chan bool)
+	stopped := make(chan bool)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			//move the cursor to the top left corner and clear the screen
+			fmt.Print("\033[H\033[2J")
+			printStatus()
+			fmt.Println()
+			printQueue()
+			fmt.Println()
+			printDownloads()
+			fmt.Printf("\nRefreshing every %s, press enter to stop\n", interval)
+
+			select {
+			case <-stop:
+				stopped <- true
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	ok := scanner.Scan()
+	stop <- true
+	<-stopped
+	return ok
+}
@@ -0,0 +1,31 @@
+package server
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/procrastimax/goparty/mp3"
+	"github.com/procrastimax/goparty/youtube"
+)
+
+//checkDuplicate checks whether the song is already in the queue, is being downloaded or was played recently
+//a song which is already in the queue gets upvoted by the user instead
+//returns a message for the user and true when the song must not be added
+func checkDuplicate(ip userIP, key mp3.SongKey) (string, bool) {
+	if song, position, ok := mp3.UpvoteDuplicate(key, ip.String()); ok {
+		if position == 0 {
+			return fmt.Sprintf("%s is playing right now!", song.SongName), true
+		}
+		return fmt.Sprintf("%s is already in the queue at position %d, your request was counted as upvote!", song.SongName, position), true
+	}
+
+	if len(key.VideoID) > 0 && youtube.IsPending(key.VideoID) {
+		return "This song is already being downloaded and will be in the queue soon!", true
+	}
+
+	if entry, ok := mp3.GetRecentlyPlayed(key); ok {
+		wait := time.Until(entry.End.Add(mp3.GetDuplicateCooldown())).Round(time.Second)
+		return fmt.Sprintf("%s was played at %s, please try again in %s!", entry.SongName, entry.End.Format("15:04"), formatDuration(wait)), true
+	}
+	return "", false
+}
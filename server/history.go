@@ -0,0 +1,87 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/procrastimax/goparty/mp3"
+)
+
+type historyUI struct {
+	Entries []mp3.HistoryEntry
+	//Admin is set for the admin, only the admin can export the history, because it contains file paths and IPs
+	Admin bool
+}
+
+//FormatTime formats the start time of a played song
+func (ui historyUI) FormatTime(t time.Time) string {
+	return t.Format("Mon 15:04")
+}
+
+//FormatDuration formats the duration a song was played
+func (ui historyUI) FormatDuration(d time.Duration) string {
+	return formatDuration(d)
+}
+
+//historyHandler shows the play history, the newest song comes first
+//with the 'format' query value (json, csv or m3u) the history gets exported as file, only the admin is allowed to export it
+func historyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "405 - Only GET methods are supported for /history", http.StatusMethodNotAllowed)
+		return
+	}
+
+	entries := mp3.GetHistory()
+	admin := isAdmin(getRequestIP(r))
+
+	if format := r.FormValue("format"); len(format) != 0 {
+		if admin == false {
+			http.Error(w, "403 - Only the admin is allowed to export the history", http.StatusForbidden)
+			return
+		}
+		writeHistoryExport(w, format, entries, true)
+		return
+	}
+
+	var ui historyUI
+	ui.Admin = admin
+	ui.Entries = make([]mp3.HistoryEntry, len(entries))
+	for i := range entries {
+		ui.Entries[i] = entries[len(entries)-1-i]
+	}
+	renderTemplate(w, "history", ui)
+}
+
+//writeHistoryExport writes the history entries in the given format (json, csv or m3u)
+//when asFile is set, the browser is told to download the export as file
+func writeHistoryExport(w http.ResponseWriter, format string, entries []mp3.HistoryEntry, asFile bool) {
+	var contentType string
+	var write func(w http.ResponseWriter) error
+
+	switch format {
+	case "json":
+		contentType = "application/json"
+		write = func(w http.ResponseWriter) error { return mp3.WriteHistoryJSON(w, entries) }
+	case "csv":
+		contentType = "text/csv"
+		write = func(w http.ResponseWriter) error { return mp3.WriteHistoryCSV(w, entries) }
+	case "m3u":
+		contentType = "audio/x-mpegurl"
+		write = func(w http.ResponseWriter) error { return mp3.WriteHistoryM3U(w, entries) }
+	default:
+		http.Error(w, "400 - Unknown format, use json, csv or m3u", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	if asFile {
+		filename := fmt.Sprintf("goparty-history-%s.%s", time.Now().Format("2006-01-02"), format)
+		w.Header().Set("Content-Disposition", "attachment; filename=\""+filename+"\"")
+	}
+
+	err := write(w)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/procrastimax/goparty/clients"
+	"github.com/procrastimax/goparty/logging"
+	"github.com/procrastimax/goparty/mp3"
+	"github.com/procrastimax/goparty/youtube"
+)
+
+const (
+	//songDurationTolerance is the time a song can be longer than the limit, trimmed songs are slightly longer because mp3 frames are not cut
+	songDurationTolerance = 2 * time.Second
+)
+
+/**
+	Limits keep a single guest from flooding the queue and the download worker.
+	All limits are set in the config, a limit of 0 disables it. The admin is not limited.
+**/
+
+//setDownloadLimits passes the limits for youtube videos to the download worker
+func setDownloadLimits() {
+	cfg := getConfig()
+	youtube.SetLimits(youtube.Limits{
+		MaxDuration:        time.Duration(cfg.MaxSongDuration) * time.Minute,
+		TrimLongVideos:     cfg.TrimLongSongs,
+		AllowAgeRestricted: cfg.AllowAgeRestricted,
+	}, func(ip string) bool {
+		return isAdmin(userIP(ip)) == false
+	})
+}
+
+//checkSubmissionLimits returns an error explaining why the user is not allowed to add another song right now
+func checkSubmissionLimits(ip userIP) error {
+	if isAdmin(ip) {
+		return nil
+	}
+
+	cfg := getConfig()
+	if cfg.MaxQueueLength > 0 && len(mp3.GetCurrentPlaylist())+youtube.GetPendingCount() >= cfg.MaxQueueLength {
+		return fmt.Errorf("The queue is full (%d songs), please wait until some songs were played!", cfg.MaxQueueLength)
+	}
+
+	if cfg.MaxPendingSongs > 0 {
+		if properties := clients.GetUserAddedSongs(ip.String()); properties != nil {
+			if properties.PlaylistSongs+properties.DownloadingSongs >= cfg.MaxPendingSongs {
+				return fmt.Errorf("You already have %d songs waiting to be played, please wait until one of them was played!", cfg.MaxPendingSongs)
+			}
+		}
+	}
+
+	if cfg.MaxSubmissions > 0 && cfg.SubmissionWindow > 0 {
+		window := time.Duration(cfg.SubmissionWindow) * time.Minute
+		count, oldest := clients.GetSubmissions(ip.String(), window)
+		if count >= cfg.MaxSubmissions {
+			wait := time.Until(oldest.Add(window)).Round(time.Second)
+			return fmt.Errorf("You can only add %d songs every %d minutes, please try again in %s!", cfg.MaxSubmissions, cfg.SubmissionWindow, formatDuration(wait))
+		}
+	}
+	return nil
+}
+
+//checkSongDuration returns an error when the song is longer than allowed for the user
+func checkSongDuration(ip userIP, filePath string) error {
+	cfg := getConfig()
+	if isAdmin(ip) || cfg.MaxSongDuration <= 0 {
+		return nil
+	}
+
+	duration, err := mp3.GetSongDuration(filePath)
+	if err != nil {
+		return err
+	}
+
+	if duration > time.Duration(cfg.MaxSongDuration)*time.Minute+songDurationTolerance {
+		return fmt.Errorf("The song is too long (%s), songs can be at most %d minutes long!", formatDuration(duration), cfg.MaxSongDuration)
+	}
+	return nil
+}
+
+//addDownloadedSong is the callback of the download worker, it adds a downloaded song to the queue when the song is not too long
+//songs which are too long are only added to the song database, the download worker must not get an error for them
+//songs which are already in the queue are upvoted instead, songs which cannot be added are reported to the user
+//songs of banned users are only added to the song database
+func addDownloadedSong(songDir, filename, ip string, newSong bool) error {
+	err := checkSongDuration(userIP(ip), songDir+filename)
+	if banned, _ := clients.IsBanned(ip); banned {
+		err = fmt.Errorf("The song %s was not added, because you are banned!", filename)
+	}
+	if err == nil {
+		if msg, duplicate := checkDuplicate(userIP(ip), mp3.SongKey{FilePath: songDir + filename}); duplicate {
+			err = fmt.Errorf("%s", msg)
+		}
+	}
+
+	if err == nil {
+		err = mp3.AddMP3ToMusicQueue(songDir, filename, ip, newSong)
+		if err == nil {
+			return nil
+		}
+	}
+	if newSong {
+		mp3.AddSongToDB(songDir, filename)
+	}
+
+	//the user sees why the song was not added on the next visit of the website
+	logging.Warn("Song is not added to the queue", "file", filename, "ip", ip, "err", err)
+	clients.AddNotice(ip, err.Error())
+	return nil
+}
@@ -0,0 +1,196 @@
+package server
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+/**
+	The settings of the program are taken from (highest precedence first):
+	command-line flags, environment variables, the config file and the default values.
+	Every config value can be overridden, f.e. 'maxQueueLength' with the flag --max-queue-length or the environment variable GOPARTY_MAX_QUEUE_LENGTH.
+	Overridden values are not written to the config file.
+**/
+
+const (
+	//envPrefix is the prefix of all environment variables read by the program
+	envPrefix = "GOPARTY_"
+	//maskedPassword replaces passwords when the config is printed
+	maskedPassword = "********"
+)
+
+//Options are the settings of the program which are not part of the config file
+type Options struct {
+	//ConfigPath is the path of the config file
+	ConfigPath string
+	//ListenAddr is the address the website is served on, f.e. ":8080"
+	ListenAddr string
+	//UserNameFile is the file with the names given to the users
+	UserNameFile string
+	//TemplateDir is a directory with custom html templates, they replace the embedded templates with the same name
+	TemplateDir string
+	//PrintConfig when set, then the config with all overrides is printed instead of starting the program
+	PrintConfig bool
+	//Setup when set, then the setup wizard runs before the program starts, it also runs on the first start
+	Setup bool
+	//Overrides are config values set by flags or environment variables by their json name, they take precedence over the config file
+	Overrides map[string]string
+}
+
+//overrideFlag is a command-line flag which overrides a value of the config
+type overrideFlag struct {
+	name      string
+	isBool    bool
+	overrides map[string]string
+}
+
+func (f *overrideFlag) String() string {
+	return ""
+}
+
+func (f *overrideFlag) Set(value string) error {
+	f.overrides[f.name] = value
+	return nil
+}
+
+//IsBoolFlag allows setting bool config values without a value, f.e. --all-user-admin
+func (f *overrideFlag) IsBoolFlag() bool {
+	return f.isBool
+}
+
+//ParseOptions reads the options and the config overrides from the environment variables and the given command-line arguments
+//flags take precedence over environment variables
+func ParseOptions(args []string) (Options, error) {
+	homedir, err := os.UserHomeDir()
+	if err != nil {
+		return Options{}, fmt.Errorf("ParseOptions: %s", err)
+	}
+
+	opts := Options{
+		ConfigPath:   getEnv("CONFIG", filepath.Join(homedir, ".config", "goparty", "config.json")),
+		ListenAddr:   getEnv("LISTEN", ":8080"),
+		UserNameFile: getEnv("USERNAMES", "usernames.txt"),
+		TemplateDir:  getEnv("TEMPLATES", ""),
+		Overrides:    make(map[string]string),
+	}
+
+	flags := flag.NewFlagSet("goparty", flag.ContinueOnError)
+	flags.StringVar(&opts.ConfigPath, "config", opts.ConfigPath, "path of the config file (env "+envPrefix+"CONFIG)")
+	flags.StringVar(&opts.ListenAddr, "listen", opts.ListenAddr, "address the website is served on (env "+envPrefix+"LISTEN)")
+	flags.StringVar(&opts.UserNameFile, "usernames", opts.UserNameFile, "file with the names given to the users (env "+envPrefix+"USERNAMES)")
+	flags.StringVar(&opts.TemplateDir, "templates", opts.TemplateDir, "directory with custom html templates which replace the embedded ones (env "+envPrefix+"TEMPLATES)")
+	flags.BoolVar(&opts.PrintConfig, "print-config", false, "print the config with all overrides and exit")
+	flags.BoolVar(&opts.Setup, "setup", false, "ask for the most important settings and write them into the config file before starting")
+
+	configType := reflect.TypeOf(Config{})
+	for i := 0; i < configType.NumField(); i++ {
+		name := configType.Field(i).Tag.Get("json")
+		if value, ok := os.LookupEnv(envPrefix + toEnvName(name)); ok {
+			opts.Overrides[name] = value
+		}
+		flags.Var(&overrideFlag{name, configType.Field(i).Type.Kind() == reflect.Bool, opts.Overrides}, toFlagName(name),
+			fmt.Sprintf("overrides '%s' of the config (env %s%s)", name, envPrefix, toEnvName(name)))
+	}
+
+	err = flags.Parse(args)
+	if err != nil {
+		return Options{}, err
+	}
+	if flags.NArg() > 0 {
+		return Options{}, fmt.Errorf("ParseOptions: unexpected argument %s", flags.Arg(0))
+	}
+	return opts, nil
+}
+
+//getEnv returns the value of the environment variable with the given name after the prefix, or the fallback when it is not set
+func getEnv(name string, fallback string) string {
+	if value, ok := os.LookupEnv(envPrefix + name); ok {
+		return value
+	}
+	return fallback
+}
+
+//splitName splits the camel case json name of a config value into its lower case words
+//runs of capital letters are one word, f.e. logMaxSizeMB to log, max, size, mb
+func splitName(name string) []string {
+	runes := []rune(name)
+	words := make([]string, 0)
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		if unicode.IsUpper(runes[i]) == false {
+			continue
+		}
+		//a capital letter starts a new word after a lower case letter, or ends a run of capital letters when a lower case letter follows
+		if unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			words = append(words, strings.ToLower(string(runes[start:i])))
+			start = i
+		}
+	}
+	return append(words, strings.ToLower(string(runes[start:])))
+}
+
+//toFlagName converts the json name of a config value to its flag name, f.e. maxQueueLength to max-queue-length
+func toFlagName(name string) string {
+	return strings.Join(splitName(name), "-")
+}
+
+//toEnvName converts the json name of a config value to its environment variable name without prefix, f.e. maxQueueLength to MAX_QUEUE_LENGTH
+func toEnvName(name string) string {
+	return strings.ToUpper(strings.Join(splitName(name), "_"))
+}
+
+//applyOverrides sets the config values which are given by their json name
+func applyOverrides(cfg *Config, overrides map[string]string) error {
+	value := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		name := value.Type().Field(i).Tag.Get("json")
+		override, ok := overrides[name]
+		if ok == false {
+			continue
+		}
+
+		field := value.Field(i)
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(override)
+		case reflect.Int:
+			number, err := strconv.Atoi(override)
+			if err != nil {
+				return fmt.Errorf("applyOverrides: %s needs to be a number, got %q", name, override)
+			}
+			field.SetInt(int64(number))
+		case reflect.Bool:
+			enabled, err := strconv.ParseBool(override)
+			if err != nil {
+				return fmt.Errorf("applyOverrides: %s needs to be true or false, got %q", name, override)
+			}
+			field.SetBool(enabled)
+		}
+	}
+	return nil
+}
+
+//PrintConfig prints the config with all overrides of the options as json, the icecast password is masked
+func PrintConfig(opts Options) error {
+	cfg, err := readConfig(opts.ConfigPath, opts.Overrides)
+	if err != nil {
+		return fmt.Errorf("PrintConfig: %s", err)
+	}
+	if len(cfg.IcecastPassword) > 0 {
+		cfg.IcecastPassword = maskedPassword
+	}
+
+	out, err := json.MarshalIndent(cfg, "", " ")
+	if err != nil {
+		return fmt.Errorf("PrintConfig: %s", err)
+	}
+	fmt.Println(string(out))
+	return nil
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/procrastimax/goparty/logging"
+	"github.com/procrastimax/goparty/mp3"
+)
+
+const (
+	//maxPlaylistSize is the maximum size of an uploaded playlist file
+	maxPlaylistSize = 1 << 20
+)
+
+//playlistImportHandler adds the songs of an uploaded m3u, m3u8 or pls playlist to the queue
+//the songs are added for the user given by the 'user' form value, by default for the admin
+func playlistImportHandler(w http.ResponseWriter, r *http.Request) {
+	ip := getRequestIP(r)
+
+	if r.Method != "POST" {
+		http.Error(w, "405 - Only POST methods are supported for /playlist/import", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if isAdmin(ip) == false {
+		renderTemplate(w, "error", errorUI{ErrorMsg: "Only the admin is allowed to import playlists!"})
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxPlaylistSize)
+	file, header, err := r.FormFile("playlist")
+	if err != nil {
+		renderTemplate(w, "error", errorUI{ErrorMsg: "Could not read the playlist: " + err.Error()})
+		return
+	}
+	defer file.Close()
+
+	entries, err := mp3.ParsePlaylist(file, header.Filename, "")
+	if err != nil {
+		renderTemplate(w, "error", errorUI{ErrorMsg: "Could not read the playlist: " + err.Error()})
+		return
+	}
+
+	user := r.FormValue("user")
+	if len(user) == 0 {
+		user = ip.String()
+	}
+
+	added, missing := mp3.ImportPlaylist(entries, user)
+	logging.Info("Imported playlist", "playlist", header.Filename, "songs", added)
+
+	if len(missing) > 0 {
+		renderTemplate(w, "error", errorUI{ErrorMsg: fmt.Sprintf("Added %d songs, the following songs are not in the song database: %s", added, strings.Join(missing, ", "))})
+		return
+	}
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+//playlistExportHandler exports the current queue as m3u playlist
+//with the 'dir' or 'filter' query values a selection of the song database is exported instead
+//the playlist contains the paths of the song files on the host, so only the admin can export it
+func playlistExportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "405 - Only GET methods are supported for /playlist/export", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if isAdmin(getRequestIP(r)) == false {
+		renderTemplate(w, "error", errorUI{ErrorMsg: "Only the admin is allowed to export playlists!"})
+		return
+	}
+
+	dir := r.FormValue("dir")
+	filter := r.FormValue("filter")
+
+	var entries []mp3.PlaylistEntry
+	name := "queue"
+	if len(dir) > 0 || len(filter) > 0 {
+		entries = mp3.GetSongDBEntries(dir, filter)
+		name = "songdb"
+	} else {
+		entries = mp3.GetQueueEntries()
+	}
+
+	filename := fmt.Sprintf("goparty-%s-%s.m3u", name, time.Now().Format("2006-01-02"))
+	w.Header().Set("Content-Type", "audio/x-mpegurl")
+	w.Header().Set("Content-Disposition", "attachment; filename=\""+filename+"\"")
+
+	err := mp3.WriteM3U(w, entries)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+//importPlaylistFile adds the songs of a playlist file to the queue, used by the console
+//the songs are added for the admin when no user IP is given
+func importPlaylistFile(filename string, args []string) error {
+	user := "127.0.0.1"
+	if len(args) > 0 {
+		user = args[0]
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("import: %s", err)
+	}
+	defer file.Close()
+
+	entries, err := mp3.ParsePlaylist(file, filename, filepath.Dir(filename))
+	if err != nil {
+		return fmt.Errorf("import: %s", err)
+	}
+
+	added, missing := mp3.ImportPlaylist(entries, user)
+	fmt.Printf("Imported %d songs of playlist %s\n", added, filename)
+	for _, entry := range missing {
+		fmt.Println(" - not in song database: " + entry)
+	}
+	return nil
+}
+
+//exportQueueFile writes the current queue as m3u playlist to the given file, used by the console
+func exportQueueFile(filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("export: %s", err)
+	}
+	defer file.Close()
+
+	err = mp3.WriteM3U(file, mp3.GetQueueEntries())
+	if err != nil {
+		return fmt.Errorf("export: %s", err)
+	}
+	fmt.Println("Exported queue to " + filename)
+	return nil
+}
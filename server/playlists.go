@@ -0,0 +1,133 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/procrastimax/goparty/clients"
+	"github.com/procrastimax/goparty/logging"
+	"github.com/procrastimax/goparty/mp3"
+	"github.com/procrastimax/goparty/playlists"
+	"github.com/procrastimax/goparty/youtube"
+)
+
+type playlistUI struct {
+	Name    string
+	Entries []string
+}
+
+//Text returns the entries of the playlist as text with one entry per line
+func (p playlistUI) Text() string {
+	return strings.Join(p.Entries, "\n")
+}
+
+type playlistsUI struct {
+	IP        string
+	Users     map[string]string
+	Playlists []playlistUI
+}
+
+//playlistsHandler shows the saved playlists on GET requests
+//on POST requests the 'action' form value (save, savequeue, delete or enqueue) changes a playlist or adds it to the queue
+func playlistsHandler(w http.ResponseWriter, r *http.Request) {
+	ip := getRequestIP(r)
+
+	if isAdmin(ip) == false {
+		renderTemplate(w, "error", errorUI{ErrorMsg: "Only the admin can edit the playlists!"})
+		return
+	}
+
+	if r.Method == "GET" {
+		ui := playlistsUI{IP: ip.String(), Users: clients.GetUsers()}
+		for _, name := range playlists.GetNames() {
+			entries, _ := playlists.Get(name)
+			ui.Playlists = append(ui.Playlists, playlistUI{Name: name, Entries: entries})
+		}
+		renderTemplate(w, "playlists", ui)
+		return
+	} else if r.Method != "POST" {
+		http.Error(w, "405 - Only GET and POST methods are supported for /playlists", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var err error
+	name := r.FormValue("name")
+	newName := strings.TrimSpace(r.FormValue("newName"))
+
+	switch r.FormValue("action") {
+	case "save":
+		if len(name) > 0 && name != newName {
+			err = playlists.Rename(name, newName)
+			if err != nil {
+				break
+			}
+		}
+		err = playlists.Save(newName, strings.Split(r.FormValue("entries"), "\n"))
+	case "savequeue":
+		entries := make([]string, 0)
+		for _, entry := range mp3.GetQueueEntries() {
+			entries = append(entries, entry.Path)
+		}
+		err = playlists.Save(newName, entries)
+	case "delete":
+		err = playlists.Delete(name)
+	case "enqueue":
+		user := r.FormValue("user")
+		if len(user) == 0 {
+			user = ip.String()
+		}
+		var missing []string
+		_, missing, err = enqueuePlaylist(name, user)
+		if err == nil && len(missing) > 0 {
+			err = fmt.Errorf("The following songs are not in the song database or are blocked: %s", strings.Join(missing, ", "))
+		}
+	default:
+		err = fmt.Errorf("Unknown playlist action")
+	}
+
+	if err != nil {
+		renderTemplate(w, "error", errorUI{ErrorMsg: err.Error()})
+		return
+	}
+	http.Redirect(w, r, "/playlists", http.StatusFound)
+}
+
+//enqueuePlaylist adds all entries of a saved playlist to the queue for the given user
+//youtube links which were downloaded before are added directly, all other youtube links are downloaded first
+//returns the count of added songs and the entries which could not be added, f.e. because they are blocked
+func enqueuePlaylist(name string, user string) (int, []string, error) {
+	entries, ok := playlists.Get(name)
+	if ok == false {
+		return 0, nil, fmt.Errorf("enqueuePlaylist: playlist %s does not exist", name)
+	}
+
+	added := 0
+	missing := make([]string, 0)
+	for _, entry := range entries {
+		if validYoutubeLink.MatchString(entry) {
+			downloadPath := getConfig().DownloadPath
+			filename, err := mp3.CheckYTSongInDB(entry, downloadPath)
+			if err == nil && len(filename) > 0 && mp3.AddMP3ToMusicQueue(downloadPath, filename, user, false) == nil {
+				added++
+				continue
+			}
+			if youtube.Add(entry, user) != nil {
+				missing = append(missing, entry)
+				continue
+			}
+			added++
+			continue
+		}
+
+		dir, filename, ok := mp3.ResolvePlaylistEntry(entry)
+		if ok == false || mp3.AddMP3ToMusicQueue(dir, filename, user, false) != nil {
+			missing = append(missing, entry)
+			continue
+		}
+		added++
+	}
+
+	logging.Info("Added saved playlist to the queue", "playlist", name, "songs", added)
+	return added, missing, nil
+}
@@ -0,0 +1,135 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/procrastimax/goparty/logging"
+	"github.com/procrastimax/goparty/mp3"
+)
+
+/**
+	The config is reloaded when the config file changes, when the program receives a SIGHUP
+	or when the admin reloads it on the admin page or the console. The queue is kept.
+**/
+
+const (
+	//configWatchInterval is the interval in which the config file is checked for changes
+	configWatchInterval = 3 * time.Second
+)
+
+var (
+	reloadMutex sync.Mutex
+	//configModTime is the modification time of the config file when it was read the last time
+	configModTime time.Time
+)
+
+//getConfigModTime returns the modification time of the config file, zero when the file cannot be read
+func getConfigModTime() time.Time {
+	info, err := os.Stat(configPath)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+//watchConfig reloads the config whenever the config file changes or the program receives a SIGHUP
+func watchConfig() {
+	reloadMutex.Lock()
+	configModTime = getConfigModTime()
+	reloadMutex.Unlock()
+
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	ticker := time.NewTicker(configWatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-hangup:
+			logging.Info("Received SIGHUP")
+		case <-ticker.C:
+			reloadMutex.Lock()
+			modTime := configModTime
+			reloadMutex.Unlock()
+			if getConfigModTime().Equal(modTime) {
+				continue
+			}
+			logging.Info("Config file changed", "path", configPath)
+		}
+
+		err := reloadConfig()
+		if err != nil {
+			logging.Error("Could not reload the config", "err", err)
+		}
+	}
+}
+
+//reloadConfig reads the config file again, validates it and applies the changed settings to the running program
+//the download path and the output are only changed after a restart, an invalid config is not applied
+func reloadConfig() error {
+	reloadMutex.Lock()
+	defer reloadMutex.Unlock()
+
+	//the modification time is remembered first, so an invalid config is not read again until it changes
+	configModTime = getConfigModTime()
+
+	cfg, err := readConfig(configPath, options.Overrides)
+	if err != nil {
+		return fmt.Errorf("reloadConfig: %s", err)
+	}
+	err = validateConfig(cfg)
+	if err != nil {
+		return fmt.Errorf("reloadConfig: %s", err)
+	}
+
+	for _, warning := range getConfigWarnings(cfg) {
+		logging.Warn("Config: " + warning)
+	}
+	running := getConfig()
+	for _, name := range keepRestartSettings(running, cfg) {
+		logging.Warn("The changed setting is used after a restart of the program", "setting", name)
+	}
+
+	rescan := cfg.MusicPath != running.MusicPath
+	setConfig(cfg)
+	applyConfig()
+
+	if rescan {
+		err = mp3.InitializeSongDBFromMemory(cfg.MusicPath, cfg.DownloadPath)
+		if err != nil {
+			logging.Error("Could not read the song database", "err", err)
+		}
+	}
+
+	logging.Info("Config reloaded")
+	return nil
+}
+
+//keepRestartSettings keeps the settings of the running config which are only changed after a restart in the new config
+//returns the names of the kept settings which were changed
+func keepRestartSettings(running *Config, cfg *Config) []string {
+	changed := make([]string, 0)
+	if cfg.DownloadPath != running.DownloadPath {
+		changed = append(changed, "downloadPath")
+		cfg.DownloadPath = running.DownloadPath
+	}
+	if cfg.Output != running.Output || cfg.RecordPath != running.RecordPath {
+		changed = append(changed, "output")
+		cfg.Output, cfg.RecordPath = running.Output, running.RecordPath
+	}
+	if cfg.IcecastHost != running.IcecastHost || cfg.IcecastPort != running.IcecastPort || cfg.IcecastMount != running.IcecastMount || cfg.IcecastPassword != running.IcecastPassword {
+		changed = append(changed, "icecast mount")
+		cfg.IcecastHost, cfg.IcecastPort, cfg.IcecastMount, cfg.IcecastPassword = running.IcecastHost, running.IcecastPort, running.IcecastMount, running.IcecastPassword
+	}
+	//the log level is changed right away
+	if cfg.LogFormat != running.LogFormat || cfg.LogFile != running.LogFile || cfg.LogMaxSize != running.LogMaxSize || cfg.LogMaxFiles != running.LogMaxFiles {
+		changed = append(changed, "log file")
+		cfg.LogFormat, cfg.LogFile, cfg.LogMaxSize, cfg.LogMaxFiles = running.LogFormat, running.LogFile, running.LogMaxSize, running.LogMaxFiles
+	}
+	return changed
+}
@@ -2,31 +2,44 @@
 package server
 
 import (
-	"bufio"
 	"fmt"
 	"html/template"
-	"log"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/procrastimax/goparty/blocklist"
 	"github.com/procrastimax/goparty/clients"
+	"github.com/procrastimax/goparty/html"
+	"github.com/procrastimax/goparty/logging"
 	"github.com/procrastimax/goparty/mp3"
+	"github.com/procrastimax/goparty/playlists"
 	"github.com/procrastimax/goparty/youtube"
 )
 
 var (
-	templates        = template.Must(template.ParseFiles("html/user.html", "html/admin.html", "html/error.html", "html/songdb.html"))
-	validPath        = regexp.MustCompile("^/(start|skip|pause|stop)")
-	validYoutubeLink = regexp.MustCompile("(https{0,1}://www\\.youtube\\.com/watch\\?v=\\S*|https{0,1}://youtu\\.be/\\S*)")
-	serverIP         string
-	config           *Config
-
-	//configPath for unix systems
-	configPath = ".config" + string(os.PathSeparator) + "goparty" + string(os.PathSeparator) + "config.json"
+	templates         *template.Template
+	templateFiles     = []string{"user.html", "admin.html", "error.html", "songdb.html", "history.html", "stats.html", "playlists.html", "blocklist.html"}
+	validPath         = regexp.MustCompile("^/(start|skip|pause|stop)")
+	validSeekPosition = regexp.MustCompile("^([+-]?)(?:(\\d+):)?(\\d+)$")
+	validYoutubeLink  = regexp.MustCompile("(https{0,1}://www\\.youtube\\.com/watch\\?v=\\S*|https{0,1}://youtu\\.be/\\S*)")
+	serverIP          string
+	config            *Config
+	options           Options
+
+	//configPath is the path of the config file, on unix systems ~/.config/goparty/config.json by default
+	configPath string
+)
+
+const (
+	//seekStep is the duration the rewind and forward admin tasks jump in the current song
+	seekStep = 10 * time.Second
 )
 
 type userIP string
@@ -36,10 +49,36 @@ type errorUI struct {
 }
 
 type queueUI struct {
-	Name    string
-	IP      string
-	AdminIP string
-	Songs   []mp3.Song
+	Name      string
+	IP        string
+	AdminIP   string
+	Songs     []mp3.Song
+	State     string
+	Volume    int
+	Muted     bool
+	Position  string
+	Duration  string
+	Stream    bool
+	Users     map[string]string
+	Bans      map[string]time.Time
+	Notices   []string
+	Downloads []youtube.PendingDownload
+
+	//OutputError is the error of the output which stopped the music, empty when the output did not fail
+	OutputError string
+}
+
+//FormatBanEnd describes when the ban of a user ends
+func (ui queueUI) FormatBanEnd(until time.Time) string {
+	return formatBanEnd(until)
+}
+
+//FormatDuration formats the duration of a song, unknown durations are empty
+func (ui queueUI) FormatDuration(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+	return formatDuration(d)
 }
 
 func (ui queueUI) IsSongUpvotedByUser(songID int) bool {
@@ -53,6 +92,8 @@ func (ui queueUI) IsSongUpvotedByUser(songID int) bool {
 
 type songdbUI struct {
 	Songs []string
+	//Admin is true when the user can export the song folders as playlists
+	Admin bool
 }
 
 func (db songdbUI) IncreaseID(id int) int {
@@ -68,6 +109,20 @@ func (db songdbUI) IsDirectory(path string) bool {
 	return false
 }
 
+//getRequestIP returns the IP of the user who sent the request
+func getRequestIP(r *http.Request) userIP {
+	//convert localhost ipv6 resolution to an ipv4 address
+	if strings.Contains(r.RemoteAddr, "::1") {
+		return "127.0.0.1"
+	}
+	return userIP(r.RemoteAddr).normalizeIP()
+}
+
+//isAdmin returns true when the user with the given IP is allowed to control the music
+func isAdmin(ip userIP) bool {
+	return strings.Contains(ip.String(), "127.0.0.1") || getConfig().AllUserAdmin
+}
+
 func renderTemplate(w http.ResponseWriter, tmpl string, data interface{}) {
 	err := templates.ExecuteTemplate(w, tmpl+".html", data)
 	if err != nil {
@@ -77,27 +132,44 @@ func renderTemplate(w http.ResponseWriter, tmpl string, data interface{}) {
 
 func viewHandler(w http.ResponseWriter, r *http.Request) {
 	var uidata queueUI
-	var ip userIP
-	//convert localhost ipv6 resolution to an ipv4 address
-	if strings.Contains(r.RemoteAddr, "::1") {
-		ip = "127.0.0.1"
-	} else {
-		ip = userIP(r.RemoteAddr)
-		ip = ip.normalizeIP()
-	}
+	ip := getRequestIP(r)
 
 	uidata.Name = clients.GetUserNameToIP(ip.String())
 	uidata.Songs = mp3.GetCurrentPlaylist()
 	uidata.IP = ip.String()
-	uidata.AdminIP = serverIP
+	uidata.AdminIP = serverIP + ":" + getListenPort()
+	uidata.State = mp3.GetPlayerState().String()
+	if err := mp3.GetOutputError(); err != nil {
+		uidata.OutputError = err.Error()
+	}
+	uidata.Volume = mp3.GetVolume()
+	uidata.Muted = mp3.IsMuted()
+	uidata.Stream = mp3.GetMaxStreamListeners() > 0
+	uidata.Users = clients.GetUsers()
+	uidata.Bans = clients.GetBans()
+	uidata.Notices = clients.PopNotices(ip.String())
+	uidata.Downloads = youtube.GetPendingDownloads()
+	if position, duration, ok := mp3.GetPosition(); ok {
+		uidata.Position = formatDuration(position)
+		uidata.Duration = formatDuration(duration)
+	}
 
-	if i := r.FormValue("task"); len(i) != 0 {
+	if i := r.FormValue("task"); len(i) != 0 && isAdmin(ip) {
 		handleAdminTasks(i)
 		http.Redirect(w, r, "/", http.StatusFound)
 	}
 
+	if i := r.FormValue("seek"); len(i) != 0 && isAdmin(ip) {
+		err := seekTo(i)
+		if err != nil {
+			renderTemplate(w, "error", errorUI{ErrorMsg: err.Error()})
+			return
+		}
+		http.Redirect(w, r, "/", http.StatusFound)
+	}
+
 	if r.Method == "GET" {
-		if strings.Contains(ip.String(), "127.0.0.1") || config.AllUserAdmin {
+		if isAdmin(ip) {
 			renderTemplate(w, "admin", uidata)
 		} else {
 			renderTemplate(w, "user", uidata)
@@ -106,8 +178,26 @@ func viewHandler(w http.ResponseWriter, r *http.Request) {
 	} else if r.Method == "POST" {
 		link := r.FormValue("ytlink")
 		if validYoutubeLink.MatchString(link) {
-			fmt.Println("added: " + link)
-			youtube.Add(link, ip.String())
+			if videoID, err := mp3.GetYoutubeVideoID(link); err == nil {
+				if msg, duplicate := checkDuplicate(ip, mp3.SongKey{VideoID: videoID}); duplicate {
+					renderTemplate(w, "error", errorUI{ErrorMsg: msg})
+					return
+				}
+			}
+
+			err := checkSubmissionLimits(ip)
+			if err != nil {
+				renderTemplate(w, "error", errorUI{ErrorMsg: err.Error()})
+				return
+			}
+
+			err = youtube.Add(link, ip.String())
+			if err != nil {
+				renderTemplate(w, "error", errorUI{ErrorMsg: err.Error()})
+				return
+			}
+			logging.Info("Added youtube video to the download queue", "url", link, "ip", ip.String())
+			clients.AddSubmission(ip.String())
 
 			r.Method = "GET"
 			http.Redirect(w, r, "/", http.StatusFound)
@@ -122,30 +212,44 @@ func viewHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func handleAdminTasks(task string) {
+	var err error
 	switch task {
 	case "start":
-		mp3.StartSpeaker()
+		err = mp3.Start()
 	case "stop":
-		mp3.CloseSpeaker()
+		mp3.Stop(false)
+	case "clear":
+		mp3.Stop(true)
 	case "skip":
 		mp3.SkipSong()
+	case "block":
+		err = blockCurrentSong()
 	case "pause":
-		mp3.PauseSpeaker()
+		err = mp3.Pause()
+	case "volup":
+		mp3.ChangeVolume(mp3.VolumeStep)
+	case "voldown":
+		mp3.ChangeVolume(-mp3.VolumeStep)
+	case "mute":
+		mp3.ToggleMute()
+	case "rewind":
+		err = mp3.SeekRelative(-seekStep)
+	case "forward":
+		err = mp3.SeekRelative(seekStep)
+	case "reload":
+		err = reloadConfig()
 	default:
-		log.Println("Unknown admin task received!")
+		logging.Warn("Unknown admin task received", "task", task)
 
 	}
+
+	if err != nil {
+		logging.Error("Admin task failed", "task", task, "err", err)
+	}
 }
 
 func upvoteHandler(w http.ResponseWriter, r *http.Request) {
-	var ip userIP
-	//convert localhost ipv6 resolution to an ipv4 address
-	if strings.Contains(r.RemoteAddr, "::1") {
-		ip = "127.0.0.1"
-	} else {
-		ip = userIP(r.RemoteAddr)
-		ip = ip.normalizeIP()
-	}
+	ip := getRequestIP(r)
 
 	if r.Method == "POST" {
 		idStr := r.FormValue("id")
@@ -153,7 +257,7 @@ func upvoteHandler(w http.ResponseWriter, r *http.Request) {
 		id, err := strconv.Atoi(idStr)
 
 		if err != nil {
-			fmt.Printf("upvoteHandler: could not convert upvoted song id to int %s", err)
+			logging.Warn("upvoteHandler: could not convert upvoted song id to int", "err", err)
 		}
 		mp3.UpvoteSong(id, ip.String())
 		http.Redirect(w, r, "/", http.StatusFound)
@@ -163,18 +267,12 @@ func upvoteHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func songDBHandler(w http.ResponseWriter, r *http.Request) {
-	var ip userIP
-	//convert localhost ipv6 resolution to an ipv4 address
-	if strings.Contains(r.RemoteAddr, "::1") {
-		ip = "127.0.0.1"
-	} else {
-		ip = userIP(r.RemoteAddr)
-		ip = ip.normalizeIP()
-	}
+	ip := getRequestIP(r)
 
 	if r.Method == "GET" {
 		var dbui songdbUI
 		dbui.Songs = mp3.GetSortedSongList()
+		dbui.Admin = isAdmin(ip)
 		renderTemplate(w, "songdb", dbui)
 
 	} else if r.Method == "POST" {
@@ -182,10 +280,25 @@ func songDBHandler(w http.ResponseWriter, r *http.Request) {
 		songname := r.FormValue("offlineSongBtn")
 
 		if mp3.CheckSongInDB(songname) {
-			fmt.Println("Song exists!")
+			logging.Debug("Song exists", "song", songname)
 			filedir, complSongname := mp3.GetSongDirAndCompleteName(songname)
 
-			err := mp3.AddMP3ToMusicQueue(filedir, complSongname, ip.String(), false)
+			if msg, duplicate := checkDuplicate(ip, mp3.SongKey{FilePath: filedir + complSongname}); duplicate {
+				renderTemplate(w, "error", errorUI{ErrorMsg: msg})
+				return
+			}
+
+			err := checkSubmissionLimits(ip)
+			if err == nil {
+				err = checkSongDuration(ip, filedir+complSongname)
+			}
+			if err != nil {
+				renderTemplate(w, "error", errorUI{ErrorMsg: err.Error()})
+				return
+			}
+
+			clients.AddSubmission(ip.String())
+			err = mp3.AddMP3ToMusicQueue(filedir, complSongname, ip.String(), false)
 
 			if err != nil {
 				renderTemplate(w, "error", errorUI{ErrorMsg: "Could not add offline song: " + err.Error()})
@@ -201,30 +314,120 @@ func songDBHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+//parseTemplates parses the html templates of the website, which are embedded into the program
+//templates in the override directory replace the embedded templates with the same name, f.e. for a custom theme
+func parseTemplates(overrideDir string) error {
+	parsed := template.New("")
+	for _, name := range templateFiles {
+		content, err := readTemplate(overrideDir, name)
+		if err != nil {
+			return fmt.Errorf("parseTemplates: %s", err)
+		}
+
+		_, err = parsed.New(name).Parse(string(content))
+		if err != nil {
+			return fmt.Errorf("parseTemplates: %s", err)
+		}
+	}
+	templates = parsed
+	return nil
+}
+
+//readTemplate reads the template from the override directory, the embedded template is used when the directory does not contain it
+func readTemplate(overrideDir string, name string) ([]byte, error) {
+	if len(overrideDir) > 0 {
+		content, err := ioutil.ReadFile(filepath.Join(overrideDir, name))
+		if err == nil {
+			logging.Info("Using custom template", "path", filepath.Join(overrideDir, name))
+			return content, nil
+		} else if os.IsNotExist(err) == false {
+			return nil, err
+		}
+	}
+	return html.Templates.ReadFile(name)
+}
+
+//getListenPort returns the port the website is served on
+func getListenPort() string {
+	_, port, err := net.SplitHostPort(options.ListenAddr)
+	if err != nil {
+		return options.ListenAddr
+	}
+	return port
+}
+
 //SetupServing sets up all we need to handle our "website"
-func SetupServing() {
+func SetupServing(opts Options) {
+	options = opts
+	configPath = options.ConfigPath
+
+	if options.Setup || (needsSetup(configPath, options.Overrides) && isInteractive()) {
+		err := RunSetupWizard(configPath, os.Stdin, os.Stdout)
+		if err != nil {
+			logging.Fatal("Setup failed", "err", err)
+		}
+	}
 
-	homedir, err := os.UserHomeDir()
+	cfg, err := readConfig(configPath, options.Overrides)
+	if err == nil {
+		err = validateConfig(cfg)
+	}
 
 	if err != nil {
-		log.Fatalln("SetupServing: ", err)
+		//the log is not set up yet, the problems of the config are easier to read without the log format
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 
-	configPath = homedir + string(os.PathSeparator) + configPath
+	setConfig(cfg)
+	logSettings := getLogSettings(cfg, isInteractive())
+	err = logging.Setup(logSettings)
+	if err != nil {
+		logging.Fatal("Could not set up the log", "err", err)
+	}
+	if logSettings.File != cfg.LogFile {
+		fmt.Println("The log is written to: " + logSettings.File)
+	}
+	for _, warning := range getConfigWarnings(cfg) {
+		logging.Warn("Config: " + warning)
+	}
 
-	cfg, err := ReadConfig(configPath)
+	err = parseTemplates(options.TemplateDir)
+	if err != nil {
+		logging.Fatal("Could not parse the templates", "err", err)
+	}
 
+	err = mp3.InitLoudnessDB(filepath.Join(filepath.Dir(configPath), "loudness.json"))
 	if err != nil {
-		log.Fatalln(err)
+		logging.Error("Could not read the loudness database", "err", err)
 	}
+	mp3.SetLoudnessNormalization(cfg.NormalizeLoudness)
 
-	config = cfg
+	err = mp3.InitPlayerSettings(filepath.Join(filepath.Dir(configPath), "player.json"))
+	if err != nil {
+		logging.Error("Could not read the player settings", "err", err)
+	}
 
-	mp3.InitializeSongDBFromMemory(config.MusicPath, config.DownloadPath)
+	err = mp3.InitHistory(filepath.Join(filepath.Dir(configPath), "history.jsonl"))
+	if err != nil {
+		logging.Error("Could not read the play history", "err", err)
+	}
+
+	err = blocklist.Init(filepath.Join(filepath.Dir(configPath), "blocklist.json"))
+	if err != nil {
+		logging.Error("Could not read the blocklist", "err", err)
+	}
 
-	err = clients.InitUserNames("usernames.txt")
+	err = playlists.Init(filepath.Join(filepath.Dir(configPath), "playlists.json"))
 	if err != nil {
-		log.Println(err)
+		logging.Error("Could not read the saved playlists", "err", err)
+	}
+
+	mp3.InitializeSongDBFromMemory(cfg.MusicPath, cfg.DownloadPath)
+
+	err = clients.InitUserNames(options.UserNameFile)
+	if err != nil {
+		logging.Error("Could not read the user names", "err", err)
 	}
 
 	serverIP = getLocalServerAdress()
@@ -234,33 +437,56 @@ func SetupServing() {
 
 	setupMusic()
 
-	mp3.SetNeededUpvoteCount(config.UpvotesNeededForRanking)
+	applyConfig()
 
 	serverMux := http.NewServeMux()
 	serverMux.HandleFunc("/", viewHandler)
 	serverMux.HandleFunc("/upvote", upvoteHandler)
 	serverMux.HandleFunc("/songdb", songDBHandler)
-
-	youtube.StartDownloadWorker(config.DownloadPath, mp3.AddMP3ToMusicQueue)
+	serverMux.HandleFunc("/api/volume", apiVolumeHandler)
+	serverMux.HandleFunc("/api/position", apiPositionHandler)
+	serverMux.HandleFunc("/stream.wav", streamHandler)
+	serverMux.HandleFunc("/history", historyHandler)
+	serverMux.HandleFunc("/api/history", apiHistoryHandler)
+	serverMux.HandleFunc("/stats", statsHandler)
+	serverMux.HandleFunc("/api/stats", apiStatsHandler)
+	serverMux.HandleFunc("/playlist/import", playlistImportHandler)
+	serverMux.HandleFunc("/playlist/export", playlistExportHandler)
+	serverMux.HandleFunc("/playlists", playlistsHandler)
+	serverMux.HandleFunc("/blocklist", blocklistHandler)
+	serverMux.HandleFunc("/users", usersHandler)
+
+	setupKicking()
+	youtube.StartDownloadWorker(cfg.DownloadPath, addDownloadedSong)
+	restoreQueues()
+
+	server := &http.Server{Addr: options.ListenAddr, Handler: banMiddleware(serverMux)}
+	go handleShutdown(server)
 
 	fmt.Println(createWelcomeMessage(serverIP))
 	go handleUserInput()
+	go watchConfig()
 
-	log.Fatal(http.ListenAndServe(":8080", serverMux))
+	err = server.ListenAndServe()
+	if err != http.ErrServerClosed {
+		logging.Fatal("Could not serve the website", "address", options.ListenAddr, "err", err)
+	}
+	<-shutdownDone
+	logging.Close()
 }
 
 func getLocalServerAdress() string {
 	ifaces, err := net.Interfaces()
 	if err != nil {
 		//shutdown the program at this stage, because something with the network card must be wrong
-		log.Fatalln("Could not retrieve local music server IP address!", err)
+		logging.Fatal("Could not retrieve local music server IP address", "err", err)
 	}
 
 	for _, i := range ifaces {
 		addrs, err := i.Addrs()
 
 		if err != nil {
-			log.Fatalln("Could not retrieve local music server IP address!", err)
+			logging.Fatal("Could not retrieve local music server IP address", "err", err)
 		}
 
 		for _, addr := range addrs {
@@ -285,11 +511,45 @@ func getLocalServerAdress() string {
 }
 
 func setupMusic() {
-	err := mp3.InitSpeaker()
+	cfg := getConfig()
+	output, err := createOutput(cfg)
 	if err != nil {
-		log.Fatalln(err.Error())
+		logging.Fatal("Could not create the output", "output", cfg.Output, "err", err)
+	}
+
+	err = mp3.SetOutput(output)
+	if err != nil {
+		logging.Fatal("Could not set the output", "output", cfg.Output, "err", err)
+	}
+
+	err = mp3.Start()
+	if err != nil {
+		logging.Fatal("Could not start the player", "output", cfg.Output, "err", err)
 	}
-	mp3.StartSpeaker()
+}
+
+//createOutput returns the audio output selected in the config
+func createOutput(cfg *Config) (mp3.Output, error) {
+	switch cfg.Output {
+	case "", "speaker":
+		return mp3.NewSpeakerOutput(), nil
+	case "null":
+		return mp3.NewNullOutput(), nil
+	case "wav":
+		if len(cfg.RecordPath) == 0 {
+			return nil, fmt.Errorf("createOutput: the wav output needs a recordPath")
+		}
+		return mp3.NewWAVOutput(cfg.RecordPath), nil
+	case "icecast":
+		//the icecast settings are checked by validateConfig
+		return mp3.NewIcecastOutput(mp3.IcecastConfig{
+			Host:     cfg.IcecastHost,
+			Port:     cfg.IcecastPort,
+			Mount:    cfg.IcecastMount,
+			Password: cfg.IcecastPassword,
+		}), nil
+	}
+	return nil, fmt.Errorf("createOutput: unknown output %q, use speaker, null, wav or icecast", cfg.Output)
 }
 
 func (ip userIP) String() string {
@@ -314,67 +574,46 @@ func (ip userIP) isIPv4() bool {
 	return true
 }
 
-func createWelcomeMessage(ip string) string {
-	builder := strings.Builder{}
-	builder.WriteString("\n-------------------------------------\n")
-	builder.WriteString("GOPARTY - The Youtube Music Queue\n")
-	builder.WriteString("-------------------------------------\n\n")
-	builder.WriteString("Hello you are the admin!\n")
-	builder.WriteString("\033[0;31mYour local IP is: ")
-	builder.WriteString(ip + "\033[0m\n")
-	builder.WriteString("The config can be found under: ")
-	builder.WriteString(configPath + "\n")
-	builder.WriteString("After editing the config file you need to restart the program!")
-	builder.WriteString("\n\n")
-	builder.WriteString("Please open your webbrowser on this machine and enter: 'localhost:8080' for viewing the admin page!\n")
-	builder.WriteString("All other users can view the website under: 'IP:8080'. The IP is written above.\n\n")
-	builder.WriteString("You can enter the following commands:\n")
-	builder.WriteString("- help (shows this text)\n")
-	builder.WriteString("- play (starts paused music)\n")
-	builder.WriteString("- pause (pauses the music)\n")
-	builder.WriteString("- skip (skips the current playing song)\n")
-	builder.WriteString("- list (lists all current songs in the playing queue)\n")
-	builder.WriteString("- exit/quit (quits the program)\n")
-	return builder.String()
+//formatDuration formats a duration as minutes and seconds (m:ss)
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	return fmt.Sprintf("%d:%02d", int(d.Minutes()), int(d.Seconds())%60)
 }
 
-func handleUserInput() error {
-	scanner := bufio.NewScanner(os.Stdin)
-	for {
-		fmt.Print("> ")
-		ok := scanner.Scan()
-		switch scanner.Text() {
-		case "play":
-			mp3.StartSpeaker()
-		case "pause":
-			mp3.PauseSpeaker()
-		case "skip":
-			mp3.SkipSong()
-		case "list":
-			fmt.Println()
-			for _, song := range mp3.GetCurrentPlaylist() {
-				fmt.Println(" - " + song.SongName + "\tby: " + song.UserName + " (" + song.UserIP + ")\tupvotes: " + strconv.Itoa(song.GetUpvotesCount()))
-			}
-			fmt.Println()
-		case "help":
-			fmt.Println(createWelcomeMessage(serverIP))
-		case "exit", "quit", "q":
-			fmt.Println("Quitting the program...")
-			os.Exit(0)
-		default:
-			if len(scanner.Text()) > 0 {
-				fmt.Println("unknown command!")
-			}
-		}
+//parseSeekPosition parses a position in the format [+|-][m:]ss
+//returns the position and the sign, the sign is 0 for absolute positions and +1/-1 for relative ones
+func parseSeekPosition(value string) (time.Duration, int, error) {
+	match := validSeekPosition.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil {
+		return 0, 0, fmt.Errorf("invalid position %q, use the format [+|-][m:]ss", value)
+	}
 
-		if scanner.Err() != nil {
-			return fmt.Errorf("handleUserInput: %s", scanner.Err().Error())
-		}
+	position := time.Duration(0)
+	if len(match[2]) > 0 {
+		minutes, _ := strconv.Atoi(match[2])
+		position += time.Duration(minutes) * time.Minute
+	}
+	seconds, _ := strconv.Atoi(match[3])
+	position += time.Duration(seconds) * time.Second
+
+	switch match[1] {
+	case "+":
+		return position, 1, nil
+	case "-":
+		return position, -1, nil
+	}
+	return position, 0, nil
+}
 
-		if ok == false {
-			log.Println("Stopping listening to user input")
-			break
-		}
+//seekTo parses the given position and seeks in the currently playing song
+func seekTo(value string) error {
+	position, sign, err := parseSeekPosition(value)
+	if err != nil {
+		return err
 	}
-	return nil
+
+	if sign == 0 {
+		return mp3.Seek(position)
+	}
+	return mp3.SeekRelative(time.Duration(sign) * position)
 }
@@ -0,0 +1,204 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+/**
+	The setup wizard asks for the most important settings on the console and writes them into the config file.
+	It runs on the first start, when the paths are not set yet, or when the program is started with --setup.
+**/
+
+//needsSetup returns true when the paths are neither set in the config file nor in the overrides, f.e. on the first start
+func needsSetup(path string, overrides map[string]string) bool {
+	cfg := defaultConfig()
+	file, err := ioutil.ReadFile(path)
+	if err == nil && json.Unmarshal(file, &cfg) != nil {
+		//an invalid config file is reported when it is read
+		return false
+	}
+	applyOverrides(&cfg, overrides)
+	return len(cfg.MusicPath) == 0 || len(cfg.DownloadPath) == 0
+}
+
+//isInteractive returns true when the program reads its input from a terminal
+func isInteractive() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+//setupWizard reads the answers of the user
+type setupWizard struct {
+	scanner *bufio.Scanner
+	out     io.Writer
+}
+
+//ask asks the question and returns the answer, the fallback is returned when the user just presses enter
+func (w *setupWizard) ask(question string, fallback string) (string, error) {
+	if len(fallback) > 0 {
+		fmt.Fprintf(w.out, "%s [%s]: ", question, fallback)
+	} else {
+		fmt.Fprintf(w.out, "%s: ", question)
+	}
+
+	if w.scanner.Scan() == false {
+		if w.scanner.Err() != nil {
+			return "", w.scanner.Err()
+		}
+		return "", fmt.Errorf("the input ended before the setup was finished")
+	}
+
+	answer := strings.TrimSpace(w.scanner.Text())
+	if len(answer) == 0 {
+		return fallback, nil
+	}
+	return answer, nil
+}
+
+//askFolder asks for a folder until the user enters a usable one, missing folders are created when create is set
+func (w *setupWizard) askFolder(question string, fallback string, create bool) (string, error) {
+	for {
+		answer, err := w.ask(question, fallback)
+		if err != nil {
+			return "", err
+		}
+		path := normalizeDirPath(answer)
+		if len(path) == 0 {
+			fmt.Fprintln(w.out, "Please enter a folder!")
+			continue
+		}
+
+		if create {
+			err = os.MkdirAll(path, 0755)
+			if err == nil {
+				err = checkDir(path, true)
+			}
+		} else {
+			err = checkDir(path, false)
+		}
+		if err != nil {
+			fmt.Fprintln(w.out, err)
+			continue
+		}
+		return path, nil
+	}
+}
+
+//askNumber asks for a number which is at least min
+func (w *setupWizard) askNumber(question string, fallback int, min int) (int, error) {
+	for {
+		answer, err := w.ask(question, strconv.Itoa(fallback))
+		if err != nil {
+			return 0, err
+		}
+		number, err := strconv.Atoi(answer)
+		if err != nil || number < min {
+			fmt.Fprintf(w.out, "Please enter a number which is at least %d!\n", min)
+			continue
+		}
+		return number, nil
+	}
+}
+
+//askYesNo asks a yes/no question
+func (w *setupWizard) askYesNo(question string, fallback bool) (bool, error) {
+	fallbackAnswer := "n"
+	if fallback {
+		fallbackAnswer = "y"
+	}
+	for {
+		answer, err := w.ask(question+" (y/n)", fallbackAnswer)
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(answer) {
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+		fmt.Fprintln(w.out, "Please answer with y or n!")
+	}
+}
+
+//RunSetupWizard asks for the most important settings and writes them into the config file at the given path
+//the settings of an existing config file are kept and offered as defaults
+func RunSetupWizard(path string, in io.Reader, out io.Writer) error {
+	cfg := defaultConfig()
+	if file, err := ioutil.ReadFile(path); err == nil {
+		err = json.Unmarshal(file, &cfg)
+		if err != nil {
+			return fmt.Errorf("RunSetupWizard: the existing config %s is invalid: %s", path, err)
+		}
+	}
+
+	wizard := &setupWizard{bufio.NewScanner(in), out}
+	fmt.Fprintln(out, "\nSetting up goparty, press enter to keep the value in brackets.")
+
+	var err error
+	cfg.MusicPath, err = wizard.askFolder("Folder with your music", cfg.MusicPath, false)
+	if err != nil {
+		return fmt.Errorf("RunSetupWizard: %s", err)
+	}
+
+	downloadPath := cfg.DownloadPath
+	if len(downloadPath) == 0 {
+		downloadPath = filepath.Join(cfg.MusicPath, "goparty")
+	}
+	cfg.DownloadPath, err = wizard.askFolder("Folder for downloaded Youtube songs (created when missing)", downloadPath, true)
+	if err != nil {
+		return fmt.Errorf("RunSetupWizard: %s", err)
+	}
+
+	cfg.UpvotesNeededForRanking, err = wizard.askNumber("Upvotes needed for moving a song forward", cfg.UpvotesNeededForRanking, 1)
+	if err != nil {
+		return fmt.Errorf("RunSetupWizard: %s", err)
+	}
+
+	cfg.MaxSongDuration, err = wizard.askNumber("Maximum song length in minutes (0 for no limit)", cfg.MaxSongDuration, 0)
+	if err != nil {
+		return fmt.Errorf("RunSetupWizard: %s", err)
+	}
+
+	cfg.AllUserAdmin, err = wizard.askYesNo("Can every guest pause and skip songs?", cfg.AllUserAdmin)
+	if err != nil {
+		return fmt.Errorf("RunSetupWizard: %s", err)
+	}
+
+	//the wav and icecast outputs need more settings, they are only set in the config file
+	if cfg.Output == "" || cfg.Output == "speaker" || cfg.Output == "null" {
+		speaker, err := wizard.askYesNo("Play the music on this computer's speaker?", cfg.Output != "null")
+		if err != nil {
+			return fmt.Errorf("RunSetupWizard: %s", err)
+		}
+		if speaker {
+			cfg.Output = "speaker"
+		} else {
+			cfg.Output = "null"
+		}
+	}
+
+	file, err := json.MarshalIndent(cfg, "", " ")
+	if err != nil {
+		return fmt.Errorf("RunSetupWizard: %s", err)
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return fmt.Errorf("RunSetupWizard: %s", err)
+	}
+	err = ioutil.WriteFile(path, file, 0644)
+	if err != nil {
+		return fmt.Errorf("RunSetupWizard: %s", err)
+	}
+
+	fmt.Fprintf(out, "Saved the config to %s, all other settings can be changed there.\n\n", path)
+	return nil
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/procrastimax/goparty/logging"
+	"github.com/procrastimax/goparty/mp3"
+	"github.com/procrastimax/goparty/youtube"
+)
+
+/**
+	The program shuts down gracefully on SIGINT, SIGTERM and the exit command of the console:
+	the website stops accepting requests, the running download gets some time to finish before it is canceled,
+	the music fades out, the output is closed and the music and download queues are saved for the next start.
+	A second signal during the shutdown quits the program immediately.
+**/
+
+const (
+	//requestShutdownTimeout is the time the running requests get to finish, f.e. the wav streams are closed afterwards
+	requestShutdownTimeout = 5 * time.Second
+	//downloadShutdownTimeout is the time the running download gets to finish before it is canceled
+	downloadShutdownTimeout = 10 * time.Second
+	//fadeOutDuration is the duration the music fades out before the output is closed
+	fadeOutDuration = 2 * time.Second
+)
+
+var (
+	//shutdownRequest is used by the console to start the shutdown
+	shutdownRequest = make(chan bool, 1)
+	//shutdownDone is closed when the shutdown finished
+	shutdownDone = make(chan bool)
+)
+
+//requestShutdown starts the graceful shutdown of the program
+func requestShutdown() {
+	select {
+	case shutdownRequest <- true:
+	default:
+		//the shutdown was already requested
+	}
+}
+
+//getQueuePath returns the path of the file the music queue is saved to
+func getQueuePath() string {
+	return filepath.Join(filepath.Dir(configPath), "queue.json")
+}
+
+//getDownloadsPath returns the path of the file the download queue is saved to
+func getDownloadsPath() string {
+	return filepath.Join(filepath.Dir(configPath), "downloads.json")
+}
+
+//restoreQueues restores the music and download queues which were saved on the last shutdown
+func restoreQueues() {
+	count, err := mp3.RestoreQueue(getQueuePath())
+	if err != nil {
+		logging.Error("Could not restore the queue", "err", err)
+	}
+	if count > 0 {
+		logging.Info("Restored the queue of the last session", "songs", count)
+	}
+
+	count, err = youtube.RestoreDownloads(getDownloadsPath())
+	if err != nil {
+		logging.Error("Could not restore the downloads", "err", err)
+	}
+	if count > 0 {
+		logging.Info("Restored the downloads of the last session", "downloads", count)
+	}
+}
+
+//handleShutdown waits for a signal or the exit command and shuts the program down gracefully
+func handleShutdown(server *http.Server) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	select {
+	case sig := <-signals:
+		logging.Info("Received signal", "signal", sig.String())
+	case <-shutdownRequest:
+	}
+
+	go func() {
+		<-signals
+		logging.Warn("Quitting the program immediately")
+		os.Exit(1)
+	}()
+
+	shutdown(server)
+	close(shutdownDone)
+}
+
+//shutdown stops the website, the downloads and the music and saves the queues
+func shutdown(server *http.Server) {
+	logging.Info("Shutting down")
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestShutdownTimeout)
+	defer cancel()
+	err := server.Shutdown(ctx)
+	if err != nil {
+		//open wav streams do not end by themselves
+		server.Close()
+	}
+	logging.Info("Website stopped")
+
+	youtube.StopDownloadWorker(downloadShutdownTimeout)
+
+	mp3.FadeOut(fadeOutDuration)
+	mp3.Stop(false)
+	//the analyzed songs are stored, so they do not need to be analyzed again on the next start
+	mp3.StopLoudnessAnalysis()
+
+	err = mp3.SaveQueue(getQueuePath())
+	if err != nil {
+		logging.Error("Could not save the queue", "err", err)
+	}
+	err = youtube.SaveDownloads(getDownloadsPath())
+	if err != nil {
+		logging.Error("Could not save the downloads", "err", err)
+	}
+	logging.Info("Saved the queues, bye!")
+}
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/procrastimax/goparty/stats"
+)
+
+type statsUI struct {
+	stats.Summary
+}
+
+//FormatSince formats the time since when the statistics are collected
+func (ui statsUI) FormatSince() string {
+	return ui.Since.Format("Mon 15:04")
+}
+
+//FormatPlayTime formats the total play time as hours and minutes
+func (ui statsUI) FormatPlayTime() string {
+	playTime := time.Duration(ui.PlayTime) * time.Second
+	return fmt.Sprintf("%dh %02dmin", int(playTime.Hours()), int(playTime.Minutes())%60)
+}
+
+//Percent formats a rate between 0 and 1 as percent
+func (ui statsUI) Percent(rate float64) string {
+	return fmt.Sprintf("%.0f%%", rate*100)
+}
+
+//statsHandler shows the party statistics to the admin
+func statsHandler(w http.ResponseWriter, r *http.Request) {
+	if isAdmin(getRequestIP(r)) == false {
+		renderTemplate(w, "error", errorUI{ErrorMsg: "Only the admin can view the party statistics!"})
+		return
+	}
+
+	renderTemplate(w, "stats", statsUI{stats.GetSummary()})
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/procrastimax/goparty/mp3"
+)
+
+//streamHandler streams the currently played music as wav to the browser
+func streamHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "405 - Only GET methods are supported for the stream", http.StatusMethodNotAllowed)
+		return
+	}
+
+	listener, err := mp3.AddStreamListener()
+	if err != nil {
+		http.Error(w, "503 - Cannot listen to the stream: "+err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	defer mp3.RemoveStreamListener(listener)
+
+	w.Header().Set("Content-Type", "audio/wav")
+	w.Header().Set("Cache-Control", "no-cache, no-store")
+
+	err = mp3.WriteStreamHeader(w)
+	if err != nil {
+		return
+	}
+
+	flusher, canFlush := w.(http.Flusher)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case chunk, ok := <-listener.C:
+			if !ok {
+				return
+			}
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+			if canFlush {
+				flusher.Flush()
+			}
+		}
+	}
+}
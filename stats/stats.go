@@ -0,0 +1,207 @@
+//Package stats collects statistics about the party, f.e. who added the most songs and which songs got the most upvotes
+package stats
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/procrastimax/goparty/clients"
+)
+
+/**
+	The statistics are collected from the events of the other packages:
+	songs added to the queue, played/ skipped songs and votes from the mp3 package and downloads from the youtube package.
+	All statistics are kept in memory and cover the time since the program was started.
+**/
+
+const (
+	//topSongCount is the number of songs in the most upvoted songs list
+	topSongCount = 10
+)
+
+var (
+	mutex        sync.Mutex
+	since        = time.Now()
+	users        = make(map[string]*UserStats)
+	songVotes    = make(map[string]*SongStats)
+	playTime     time.Duration
+	playedSongs  int
+	skippedSongs int
+	youtubePlays int
+	offlinePlays int
+	downloads    int
+	cachedSongs  int
+	votes        int
+)
+
+//UserStats are the statistics of a single user
+type UserStats struct {
+	UserIP       string  `json:"userIP"`
+	UserName     string  `json:"userName"`
+	AddedSongs   int     `json:"addedSongs"`
+	PlayedSongs  int     `json:"playedSongs"`
+	SkippedSongs int     `json:"skippedSongs"`
+	Downloads    int     `json:"downloads"`
+	VotesGiven   int     `json:"votesGiven"`
+	SkipRate     float64 `json:"skipRate"`
+}
+
+//SongStats are the upvotes of a single song
+type SongStats struct {
+	SongName string `json:"song"`
+	UserName string `json:"userName"`
+	Upvotes  int    `json:"upvotes"`
+}
+
+//Summary are all statistics of the party
+type Summary struct {
+	Since        time.Time   `json:"since"`
+	PlayTime     float64     `json:"playTimeSeconds"`
+	PlayedSongs  int         `json:"playedSongs"`
+	SkippedSongs int         `json:"skippedSongs"`
+	YoutubePlays int         `json:"youtubePlays"`
+	OfflinePlays int         `json:"offlinePlays"`
+	Downloads    int         `json:"downloads"`
+	CachedSongs  int         `json:"cachedSongs"`
+	Votes        int         `json:"votes"`
+	Contributors []UserStats `json:"contributors"`
+	UpvotedSongs []SongStats `json:"upvotedSongs"`
+}
+
+//getUser returns the stats of the user, the user is created if it does not exist, the mutex must be held by the caller
+func getUser(userIP string) *UserStats {
+	user, ok := users[userIP]
+	if ok == false {
+		user = &UserStats{UserIP: userIP, UserName: clients.GetUserNameToIP(userIP)}
+		users[userIP] = user
+	}
+	return user
+}
+
+//AddSong counts a song which was added to the queue by the user
+func AddSong(userIP string) {
+	mutex.Lock()
+	getUser(userIP).AddedSongs++
+	mutex.Unlock()
+}
+
+//AddPlay counts a song which was played for the given duration
+//offline is true when the song is from the offline song collection instead of a youtube download
+func AddPlay(userIP string, duration time.Duration, skipped bool, offline bool) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	user := getUser(userIP)
+	user.PlayedSongs++
+	playedSongs++
+	playTime += duration
+
+	if skipped {
+		user.SkippedSongs++
+		skippedSongs++
+	}
+
+	if offline {
+		offlinePlays++
+	} else {
+		youtubePlays++
+	}
+}
+
+//AddVote counts an upvote of a user for the song, which was added by songUserIP
+func AddVote(userIP string, songName string, songUserIP string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	getUser(userIP).VotesGiven++
+	votes++
+
+	song, ok := songVotes[songName]
+	if ok == false {
+		song = &SongStats{SongName: songName, UserName: getUser(songUserIP).UserName}
+		songVotes[songName] = song
+	}
+	song.Upvotes++
+}
+
+//AddDownload counts a youtube song requested by the user
+//cached is true when the song was downloaded before and did not need to be downloaded again
+func AddDownload(userIP string, cached bool) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if cached {
+		cachedSongs++
+		return
+	}
+	getUser(userIP).Downloads++
+	downloads++
+}
+
+//GetSummary returns all statistics, the contributors are sorted by their added songs
+//and the upvoted songs by their upvotes, the house user is no contributor
+func GetSummary() Summary {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	summary := Summary{
+		Since:        since,
+		PlayTime:     playTime.Seconds(),
+		PlayedSongs:  playedSongs,
+		SkippedSongs: skippedSongs,
+		YoutubePlays: youtubePlays,
+		OfflinePlays: offlinePlays,
+		Downloads:    downloads,
+		CachedSongs:  cachedSongs,
+		Votes:        votes,
+		Contributors: make([]UserStats, 0, len(users)),
+		UpvotedSongs: make([]SongStats, 0, len(songVotes)),
+	}
+
+	for ip, user := range users {
+		//the songs of the house user were not added by a guest
+		if ip == clients.HouseUserIP {
+			continue
+		}
+		stats := *user
+		if stats.PlayedSongs > 0 {
+			stats.SkipRate = float64(stats.SkippedSongs) / float64(stats.PlayedSongs)
+		}
+		summary.Contributors = append(summary.Contributors, stats)
+	}
+	sort.SliceStable(summary.Contributors, func(i, j int) bool {
+		if summary.Contributors[i].AddedSongs == summary.Contributors[j].AddedSongs {
+			return summary.Contributors[i].UserName < summary.Contributors[j].UserName
+		}
+		return summary.Contributors[i].AddedSongs > summary.Contributors[j].AddedSongs
+	})
+
+	for _, song := range songVotes {
+		summary.UpvotedSongs = append(summary.UpvotedSongs, *song)
+	}
+	sort.SliceStable(summary.UpvotedSongs, func(i, j int) bool {
+		if summary.UpvotedSongs[i].Upvotes == summary.UpvotedSongs[j].Upvotes {
+			return summary.UpvotedSongs[i].SongName < summary.UpvotedSongs[j].SongName
+		}
+		return summary.UpvotedSongs[i].Upvotes > summary.UpvotedSongs[j].Upvotes
+	})
+	if len(summary.UpvotedSongs) > topSongCount {
+		summary.UpvotedSongs = summary.UpvotedSongs[:topSongCount]
+	}
+
+	return summary
+}
+
+//Reset deletes all collected statistics
+func Reset() {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	since = time.Now()
+	users = make(map[string]*UserStats)
+	songVotes = make(map[string]*SongStats)
+	playTime = 0
+	playedSongs, skippedSongs, youtubePlays, offlinePlays = 0, 0, 0, 0
+	downloads, cachedSongs, votes = 0, 0, 0
+}
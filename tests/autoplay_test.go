@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/procrastimax/goparty/mp3"
+)
+
+func TestAutoplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goparty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	houseDir := filepath.Join(dir, "house")
+	err = os.Mkdir(houseDir, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = writeSilentMP3(filepath.Join(houseDir, "house.mp3"), 400)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = writeSilentMP3(filepath.Join(dir, "guest.mp3"), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mp3.SetAutoplay("directory", "")
+	if err == nil {
+		t.Error("Expected an error for the directory autoplay without a path")
+	}
+	err = mp3.SetAutoplay("party", "")
+	if err == nil {
+		t.Error("Expected an error for an unknown autoplay mode")
+	}
+
+	err = mp3.SetAutoplay("directory", houseDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mp3.SetAutoplay("off", "")
+
+	err = mp3.SetOutput(mp3.NewNullOutput())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = mp3.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mp3.Stop(true)
+
+	if waitFor(3*time.Second, func() bool { return len(mp3.GetCurrentPlaylist()) > 0 }) == false {
+		t.Fatal("Autoplay did not queue a song for the empty queue")
+	}
+
+	songs := mp3.GetCurrentPlaylist()
+	if songs[0].UserIP != mp3.HouseUserIP || songs[0].FilePath != filepath.Join(houseDir, "house.mp3") {
+		t.Fatalf("Wrong autoplay song: %+v", songs[0])
+	}
+
+	//the next house song is queued while the first one is playing, so it is already waiting when the first one ends
+	if waitFor(3*time.Second, func() bool { return len(mp3.GetCurrentPlaylist()) == 2 }) == false {
+		t.Fatalf("Autoplay did not queue the next song: %+v", mp3.GetCurrentPlaylist())
+	}
+
+	//guest songs are played before all further house songs
+	err = mp3.AddMP3ToMusicQueue(dir+string(os.PathSeparator), "guest.mp3", "127.0.0.1", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	songs = mp3.GetCurrentPlaylist()
+	if len(songs) != 3 || songs[1].UserIP != "127.0.0.1" || songs[2].UserIP != mp3.HouseUserIP {
+		t.Fatalf("Guest song was not queued after the playing house song: %+v", songs)
+	}
+
+	mp3.SkipSong()
+	songs = mp3.GetCurrentPlaylist()
+	if len(songs) != 2 || songs[0].UserIP != "127.0.0.1" {
+		t.Fatalf("Guest song does not play after the house song: %+v", songs)
+	}
+
+	//after the guest song autoplay takes over, a house song is always waiting
+	mp3.SkipSong()
+	songs = mp3.GetCurrentPlaylist()
+	if len(songs) == 0 || songs[0].UserIP != mp3.HouseUserIP {
+		t.Fatalf("Autoplay did not continue after the guest song: %+v", songs)
+	}
+	if waitFor(3*time.Second, func() bool { return len(mp3.GetCurrentPlaylist()) == 2 }) == false {
+		t.Fatalf("Autoplay did not queue the next song: %+v", mp3.GetCurrentPlaylist())
+	}
+}
+
+func TestReadM3U(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goparty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	playlist := filepath.Join(dir, "party.m3u")
+	content := "#EXTM3U\n#EXTINF:123,Artist - Title\nsongs/first.mp3\n\n/music/second.mp3\n"
+	err = ioutil.WriteFile(playlist, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := mp3.ReadM3U(playlist)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0] != filepath.Join(dir, "songs", "first.mp3") || entries[1] != "/music/second.mp3" {
+		t.Errorf("Wrong playlist entries: %v", entries)
+	}
+}
@@ -0,0 +1,78 @@
+package tests
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/procrastimax/goparty/clients"
+	"github.com/procrastimax/goparty/mp3"
+)
+
+func TestBans(t *testing.T) {
+	clients.Ban("10.0.43.1", 0)
+	clients.Ban("10.0.43.2", time.Hour)
+	clients.Ban("10.0.43.3", time.Millisecond)
+	defer clients.Unban("10.0.43.1")
+	defer clients.Unban("10.0.43.2")
+
+	if banned, until := clients.IsBanned("10.0.43.1"); banned == false || until.IsZero() == false {
+		t.Error("User is not banned until the restart")
+	}
+	if banned, until := clients.IsBanned("10.0.43.2"); banned == false || until.Before(time.Now()) {
+		t.Error("User is not banned for an hour")
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	if banned, _ := clients.IsBanned("10.0.43.3"); banned {
+		t.Error("Ban did not expire")
+	}
+	if bans := clients.GetBans(); len(bans) != 2 {
+		t.Errorf("Expected 2 bans, got %v", bans)
+	}
+
+	if clients.Unban("10.0.43.1") == false {
+		t.Error("Ban was not lifted")
+	}
+	if banned, _ := clients.IsBanned("10.0.43.1"); banned {
+		t.Error("User is still banned")
+	}
+}
+
+func TestKick(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goparty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = writeSilentMP3(filepath.Join(dir, "song.mp3"), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mp3.Stop(true)
+
+	guest, kicked := "10.0.43.10", "10.0.43.11"
+	songDir := dir + string(os.PathSeparator)
+	for _, ip := range []string{guest, kicked, kicked, guest} {
+		err = mp3.AddMP3ToMusicQueue(songDir, "song.mp3", ip, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	clients.OnKick(mp3.RemoveUserSongs)
+	if removed := clients.Kick(kicked); removed != 2 {
+		t.Errorf("Expected 2 removed songs, got %d", removed)
+	}
+
+	songs := mp3.GetCurrentPlaylist()
+	if len(songs) != 2 || songs[0].UserIP != guest || songs[1].UserIP != guest {
+		t.Errorf("Wrong queue after kicking: %v", songs)
+	}
+	if properties := clients.GetUserAddedSongs(kicked); properties == nil || properties.PlaylistSongs != 0 {
+		t.Errorf("Song count of the kicked user was not reset: %+v", properties)
+	}
+}
@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/procrastimax/goparty/blocklist"
+	"github.com/procrastimax/goparty/clients"
+	"github.com/procrastimax/goparty/mp3"
+)
+
+func TestBlocklist(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goparty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "blocklist.json")
+	err = blocklist.Init(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer blocklist.Init("")
+
+	err = blocklist.Set(blocklist.Blocklist{Titles: []string{"("}})
+	if err == nil {
+		t.Error("Expected an error for an invalid title expression")
+	}
+
+	blockedDir := filepath.Join(dir, "blocked") + string(os.PathSeparator)
+	err = blocklist.Set(blocklist.Blocklist{
+		VideoIDs:  []string{"abcdefghijk", " "},
+		Channels:  []string{"UC123", "Meme Channel"},
+		Titles:    []string{"\\bcrazy frog\\b"},
+		FilePaths: []string{blockedDir, filepath.Join(dir, "song.mp3")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//the blocklist needs to survive a restart
+	err = blocklist.Init(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list := blocklist.Get(); len(list.VideoIDs) != 1 || len(list.Titles) != 1 {
+		t.Errorf("Wrong stored blocklist: %+v", list)
+	}
+
+	if blocklist.CheckVideo("abcdefghijk") == nil || blocklist.CheckVideo("other") != nil {
+		t.Error("Wrong result for the video check")
+	}
+	if blocklist.CheckChannel("UC123", "") == nil || blocklist.CheckChannel("", "meme channel") == nil || blocklist.CheckChannel("UC456", "Other") != nil {
+		t.Error("Wrong result for the channel check")
+	}
+	if blocklist.CheckTitle("Crazy Frog - Axel F") == nil || blocklist.CheckTitle("Crazy Frogger") != nil {
+		t.Error("Wrong result for the title check")
+	}
+	if blocklist.CheckFile(blockedDir+"any.mp3") == nil || blocklist.CheckFile(filepath.Join(dir, "other.mp3")) != nil {
+		t.Error("Wrong result for the file check")
+	}
+
+	//blocked songs cannot be added to the queue
+	err = writeSilentMP3(filepath.Join(dir, "song.mp3"), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = mp3.AddMP3ToMusicQueue(dir+string(os.PathSeparator), "song.mp3", "127.0.0.1", false)
+	if err == nil {
+		mp3.Stop(true)
+		t.Error("Blocked song was added to the queue")
+	}
+}
+
+func TestNotices(t *testing.T) {
+	ip := "10.0.41.1"
+	clients.AddNotice(ip, "first")
+	clients.AddNotice(ip, "second")
+
+	notices := clients.PopNotices(ip)
+	if len(notices) != 2 || notices[0] != "first" || notices[1] != "second" {
+		t.Errorf("Wrong notices: %v", notices)
+	}
+	if len(clients.PopNotices(ip)) != 0 {
+		t.Error("Notices were not removed")
+	}
+}
@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/procrastimax/goparty/mp3"
+)
+
+func TestNormalizeTitle(t *testing.T) {
+	titles := map[string]string{
+		"Artist - Song (Official Video)":   "artist song",
+		"ARTIST – Song [HD] {Lyrics}":      "artist song",
+		"Artist - Song - Official Audio":   "artist song",
+		"Artist feat. Other - Other Song ": "artist feat other other song",
+	}
+	for title, expected := range titles {
+		if normalized := mp3.NormalizeTitle(title); normalized != expected {
+			t.Errorf("Expected %s to be normalized to '%s', got '%s'", title, expected, normalized)
+		}
+	}
+}
+
+func TestGetYoutubeVideoID(t *testing.T) {
+	links := map[string]string{
+		"https://www.youtube.com/watch?v=abcdefghijk&list=123": "abcdefghijk",
+		"https://youtu.be/abcdefghijk?t=10":                    "abcdefghijk",
+	}
+	for link, expected := range links {
+		id, err := mp3.GetYoutubeVideoID(link)
+		if err != nil || id != expected {
+			t.Errorf("Expected video ID %s for %s, got %s (%v)", expected, link, id, err)
+		}
+	}
+}
+
+func TestDuplicates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goparty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = mp3.InitHistory(filepath.Join(dir, "history.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mp3.InitHistory("")
+
+	songDir := dir + string(os.PathSeparator)
+	files := []string{"short.mp3", "Artist - Song (Official Video)#____#abcdefghijk.mp3", "artist - song.mp3"}
+	for _, name := range files {
+		err = writeSilentMP3(filepath.Join(dir, name), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mp3.SetDuplicateDetection(time.Minute, false)
+	defer mp3.SetDuplicateDetection(0, false)
+
+	err = mp3.SetOutput(mp3.NewNullOutput())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = mp3.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mp3.Stop(true)
+
+	err = mp3.AddMP3ToMusicQueue(songDir, "short.mp3", "127.0.0.1", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if waitFor(3*time.Second, func() bool { return len(mp3.GetHistory()) == 1 }) == false {
+		t.Fatal("Played song was not added to the history")
+	}
+
+	//the song was just played, so it is in the cooldown window
+	entry, ok := mp3.GetRecentlyPlayed(mp3.SongKey{FilePath: songDir + "short.mp3"})
+	if ok == false || entry.SongName != "short" {
+		t.Errorf("Recently played song was not found: %+v", entry)
+	}
+
+	err = mp3.Pause()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = mp3.AddMP3ToMusicQueue(songDir, files[1], "10.0.40.1", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, ok = mp3.UpvoteDuplicate(mp3.SongKey{FilePath: songDir + files[2]}, "10.0.40.2")
+	if ok {
+		t.Error("Song with a different file was treated as duplicate without title matching")
+	}
+
+	song, position, ok := mp3.UpvoteDuplicate(mp3.SongKey{VideoID: "abcdefghijk"}, "10.0.40.2")
+	if ok == false || position != 0 || song.GetUpvotesCount() != 1 {
+		t.Errorf("Queued youtube song was not upvoted: %+v at %d", song, position)
+	}
+
+	mp3.SetDuplicateDetection(time.Minute, true)
+	song, _, ok = mp3.UpvoteDuplicate(mp3.SongKey{FilePath: songDir + files[2]}, "10.0.40.3")
+	if ok == false || song.GetUpvotesCount() != 2 {
+		t.Errorf("Song with the same title was not upvoted: %+v", song)
+	}
+}
@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/procrastimax/goparty/mp3"
+)
+
+//writeSilentMP3 writes a mp3 file with the given number of silent frames, every frame is around 26ms long
+func writeSilentMP3(path string, frames int) error {
+	//MPEG-1 Layer III, 128 kbit/s, 44.1 kHz frame header, the frame is 417 bytes long
+	frame := make([]byte, 417)
+	copy(frame, []byte{0xFF, 0xFB, 0x90, 0x64})
+	return ioutil.WriteFile(path, bytes.Repeat(frame, frames), 0644)
+}
+
+//waitFor polls the condition until it is true or the timeout is reached
+func waitFor(timeout time.Duration, condition func() bool) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if condition() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return condition()
+}
+
+func TestPlayHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goparty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	historyPath := filepath.Join(dir, "history.jsonl")
+	err = mp3.InitHistory(historyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mp3.InitHistory("")
+
+	songDir := dir + string(os.PathSeparator)
+	err = writeSilentMP3(filepath.Join(dir, "short.mp3"), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = writeSilentMP3(filepath.Join(dir, "long.mp3"), 400)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mp3.SetOutput(mp3.NewNullOutput())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = mp3.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mp3.Stop(true)
+
+	err = mp3.AddMP3ToMusicQueue(songDir, "short.mp3", "127.0.0.1", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = mp3.AddMP3ToMusicQueue(songDir, "long.mp3", "127.0.0.1", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if waitFor(3*time.Second, func() bool { return len(mp3.GetHistory()) == 1 }) == false {
+		t.Fatal("Played song was not added to the history")
+	}
+
+	//skip the long song as soon as it is playing
+	waitFor(3*time.Second, func() bool {
+		_, _, ok := mp3.GetPosition()
+		return ok
+	})
+	mp3.SkipSong()
+
+	history := mp3.GetHistory()
+	if len(history) != 2 {
+		t.Fatalf("Expected 2 songs in the history, got %d", len(history))
+	}
+
+	if history[0].SongName != "short" || history[0].Skipped || history[0].UserIP != "127.0.0.1" {
+		t.Errorf("Wrong history entry for the played song: %+v", history[0])
+	}
+	if history[1].SongName != "long" || history[1].Skipped == false {
+		t.Errorf("Wrong history entry for the skipped song: %+v", history[1])
+	}
+
+	//the history needs to survive a restart
+	err = mp3.InitHistory(historyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mp3.GetHistory()) != 2 {
+		t.Errorf("Expected 2 songs in the stored history, got %d", len(mp3.GetHistory()))
+	}
+
+	var csv, m3u bytes.Buffer
+	mp3.WriteHistoryCSV(&csv, mp3.GetHistory())
+	mp3.WriteHistoryM3U(&m3u, mp3.GetHistory())
+
+	if lines := strings.Split(strings.TrimSpace(csv.String()), "\n"); len(lines) != 3 {
+		t.Errorf("Expected a header and 2 lines in the csv export, got %d lines", len(lines))
+	}
+	if strings.HasPrefix(m3u.String(), "#EXTM3U") == false || strings.Contains(m3u.String(), filepath.Join(dir, "short.mp3")) == false {
+		t.Errorf("Wrong m3u export: %s", m3u.String())
+	}
+}
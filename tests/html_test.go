@@ -0,0 +1,23 @@
+package tests
+
+import (
+	"html/template"
+	"testing"
+
+	"github.com/procrastimax/goparty/html"
+)
+
+func TestEmbeddedTemplates(t *testing.T) {
+	names := []string{"user.html", "admin.html", "error.html", "songdb.html", "history.html", "stats.html", "playlists.html", "blocklist.html"}
+	for _, name := range names {
+		content, err := html.Templates.ReadFile(name)
+		if err != nil {
+			t.Errorf("Template %s is not embedded: %s", name, err)
+			continue
+		}
+		_, err = template.New(name).Parse(string(content))
+		if err != nil {
+			t.Errorf("Template %s cannot be parsed: %s", name, err)
+		}
+	}
+}
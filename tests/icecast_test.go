@@ -0,0 +1,221 @@
+package tests
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/faiface/beep"
+	"github.com/procrastimax/goparty/mp3"
+)
+
+//fakeIcecast accepts one source connection and sends the received request and the first bytes of the stream on the channels
+func fakeIcecast(t *testing.T, listener net.Listener, requests chan<- *http.Request, data chan<- int) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go func(conn net.Conn) {
+			defer conn.Close()
+			reader := bufio.NewReader(conn)
+			request, err := http.ReadRequest(reader)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			requests <- request
+
+			if request.Method == "GET" {
+				conn.Write([]byte("HTTP/1.0 200 OK\r\nContent-Length: 0\r\n\r\n"))
+				return
+			}
+
+			conn.Write([]byte("HTTP/1.1 100 Continue\r\n\r\n"))
+			buf := make([]byte, 1024)
+			n, _ := reader.Read(buf)
+			data <- n
+
+			//keep reading until the source disconnects
+			for err == nil {
+				_, err = reader.Read(buf)
+			}
+		}(conn)
+	}
+}
+
+func TestIcecastOutput(t *testing.T) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		t.Skip("ffmpeg is needed for the icecast output")
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	requests := make(chan *http.Request, 4)
+	data := make(chan int, 1)
+	go fakeIcecast(t, listener, requests, data)
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	err = mp3.SetOutput(mp3.NewIcecastOutput(mp3.IcecastConfig{Host: "127.0.0.1", Port: port, Mount: "party.mp3", Password: "hackme"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mp3.SetOutput(mp3.NewNullOutput())
+
+	err = mp3.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mp3.Stop(true)
+
+	for i := 0; i < 2; i++ {
+		select {
+		case request := <-requests:
+			user, password, ok := request.BasicAuth()
+			if ok == false || user != "source" || password != "hackme" {
+				t.Errorf("%s %s: wrong authorization", request.Method, request.URL.Path)
+			}
+
+			switch request.Method {
+			case "PUT":
+				if request.URL.Path != "/party.mp3" {
+					t.Errorf("Source connected to wrong mount %s", request.URL.Path)
+				}
+			case "GET":
+				if request.URL.Path != "/admin/metadata" || request.URL.Query().Get("mount") != "/party.mp3" {
+					t.Errorf("Wrong metadata update %s", request.URL)
+				}
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Icecast server did not receive the source connection and metadata update")
+		}
+	}
+
+	select {
+	case n := <-data:
+		if n == 0 {
+			t.Error("Icecast server received no data")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Icecast server received no data")
+	}
+}
+
+//failingOutput is an output which fails shortly after it was started
+type failingOutput struct{}
+
+func (f *failingOutput) Start(sampleRate beep.SampleRate, streamer beep.Streamer) error {
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		mp3.OutputFailed(errors.New("connection lost"))
+	}()
+	return nil
+}
+
+func (f *failingOutput) Stop() error {
+	return nil
+}
+
+func TestOutputFailure(t *testing.T) {
+	err := mp3.SetOutput(&failingOutput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mp3.SetOutput(mp3.NewNullOutput())
+
+	err = mp3.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mp3.Stop(true)
+
+	if waitFor(3*time.Second, func() bool { return mp3.GetPlayerState() == mp3.Stopped }) == false {
+		t.Fatal("Player was not stopped after the output failed")
+	}
+	if mp3.GetOutputError() == nil {
+		t.Error("Output error was not kept")
+	}
+
+	err = mp3.SetOutput(mp3.NewNullOutput())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = mp3.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mp3.GetOutputError() != nil {
+		t.Error("Output error was not reset by starting the player again")
+	}
+}
+
+func TestIcecastReconnect(t *testing.T) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		t.Skip("ffmpeg is needed for the icecast output")
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	//the server drops every source connection after the first data
+	sources := make(chan bool, 4)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				request, err := http.ReadRequest(reader)
+				if err != nil {
+					return
+				}
+				if request.Method == "GET" {
+					conn.Write([]byte("HTTP/1.0 200 OK\r\nContent-Length: 0\r\n\r\n"))
+					return
+				}
+				conn.Write([]byte("HTTP/1.1 100 Continue\r\n\r\n"))
+				reader.Read(make([]byte, 1024))
+				sources <- true
+			}(conn)
+		}
+	}()
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	err = mp3.SetOutput(mp3.NewIcecastOutput(mp3.IcecastConfig{Host: "127.0.0.1", Port: port, Mount: "party.mp3", Password: "hackme"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mp3.SetOutput(mp3.NewNullOutput())
+
+	err = mp3.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mp3.Stop(true)
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-sources:
+		case <-time.After(10 * time.Second):
+			t.Fatalf("Source did not reconnect after the connection was dropped %d times", i)
+		}
+	}
+	if mp3.GetPlayerState() != mp3.Playing {
+		t.Errorf("Player should keep playing while reconnecting, got %s", mp3.GetPlayerState())
+	}
+}
@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/procrastimax/goparty/clients"
+	"github.com/procrastimax/goparty/mp3"
+)
+
+func TestSubmissions(t *testing.T) {
+	ip := "10.0.39.1"
+	if count, _ := clients.GetSubmissions(ip, time.Minute); count != 0 {
+		t.Fatalf("Expected no submissions for a new user, got %d", count)
+	}
+
+	before := time.Now()
+	clients.AddSubmission(ip)
+	clients.AddSubmission(ip)
+
+	count, oldest := clients.GetSubmissions(ip, time.Minute)
+	if count != 2 {
+		t.Errorf("Expected 2 submissions, got %d", count)
+	}
+	if oldest.Before(before) {
+		t.Errorf("Wrong time of the oldest submission: %s", oldest)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if count, _ := clients.GetSubmissions(ip, 10*time.Millisecond); count != 0 {
+		t.Errorf("Expected the submissions to be outside of the window, got %d", count)
+	}
+}
+
+func TestGetSongDuration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goparty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	//every frame has 1152 samples at 44.1 kHz
+	path := filepath.Join(dir, "song.mp3")
+	err = writeSilentMP3(path, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	duration, err := mp3.GetSongDuration(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := time.Duration(100*1152) * time.Second / 44100
+	if duration < expected-50*time.Millisecond || duration > expected+50*time.Millisecond {
+		t.Errorf("Expected a duration of %s, got %s", expected, duration)
+	}
+
+	_, err = mp3.GetSongDuration(filepath.Join(dir, "missing.mp3"))
+	if err == nil {
+		t.Error("Expected an error for a missing song")
+	}
+}
@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/procrastimax/goparty/logging"
+)
+
+func TestLogging(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goparty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer logging.SetLevel("info")
+	defer logging.Close()
+
+	path := filepath.Join(dir, "goparty.log")
+	err = logging.Setup(logging.Settings{Level: "warn", Format: "json", File: path, MaxSizeMB: 1, MaxFiles: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logging.Info("not logged")
+	logging.Warn("Could not play the song", "song", "Quiet Song")
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scanner := bufio.NewScanner(file)
+	lines := make([]map[string]interface{}, 0)
+	for scanner.Scan() {
+		var line map[string]interface{}
+		err = json.Unmarshal(scanner.Bytes(), &line)
+		if err != nil {
+			t.Fatalf("Log line is not json: %s", scanner.Text())
+		}
+		lines = append(lines, line)
+	}
+	file.Close()
+
+	if len(lines) != 1 {
+		t.Fatalf("Expected 1 log line, got %d", len(lines))
+	}
+	if lines[0]["level"] != "WARN" || lines[0]["msg"] != "Could not play the song" || lines[0]["song"] != "Quiet Song" {
+		t.Errorf("Wrong log line: %v", lines[0])
+	}
+
+	//the log file is rotated at 1 MB, only one rotated file is kept
+	long := strings.Repeat("x", 1000)
+	for i := 0; i < 2500; i++ {
+		logging.Error("long line", "text", long)
+	}
+	files, err := filepath.Glob(path + "*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Errorf("Expected the log file and one rotated file, got %v", files)
+	}
+	for _, name := range files {
+		info, err := os.Stat(name)
+		if err == nil && info.Size() > 1024*1024 {
+			t.Errorf("%s is larger than 1 MB", name)
+		}
+	}
+
+	if logging.SetLevel("loud") == nil {
+		t.Error("Expected an error for an unknown level")
+	}
+	if logging.Setup(logging.Settings{Format: "xml"}) == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
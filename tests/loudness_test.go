@@ -0,0 +1,151 @@
+package tests
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/procrastimax/goparty/mp3"
+)
+
+func TestLoudnessGain(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goparty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer mp3.StopLoudnessAnalysis()
+
+	dbPath := filepath.Join(dir, "loudness.json")
+	db := `{
+	"quiet.mp3": {"loudness": -30, "peak": 0.1},
+	"silent.mp3": {"loudness": -60, "peak": 0.001},
+	"loud.mp3": {"loudness": -10, "peak": 1},
+	"peaking.mp3": {"loudness": -28, "peak": 0.5}
+}`
+	err = ioutil.WriteFile(dbPath, []byte(db), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = mp3.InitLoudnessDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]float64{
+		"quiet.mp3": 12,
+		//the gain is limited to 12 dB
+		"silent.mp3": 12,
+		"loud.mp3":   -8,
+		//the gain is limited by the peak, so the song does not clip
+		"peaking.mp3": -20 * math.Log10(0.5),
+	}
+	for song, gain := range expected {
+		actual, err := mp3.GetSongGain(song)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(actual-gain) > 0.0001 {
+			t.Errorf("Wrong gain for %s: %f, expected %f", song, actual, gain)
+		}
+	}
+}
+
+func TestLoudnessDB(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goparty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer mp3.StopLoudnessAnalysis()
+
+	dbPath := filepath.Join(dir, "loudness.json")
+	err = mp3.InitLoudnessDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	songPath := filepath.Join(dir, "silence.mp3")
+	err = writeSilentMP3(songPath, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gain, err := mp3.GetSongGain(songPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//a silent song is treated as a song with the target loudness
+	if gain != 0 {
+		t.Errorf("Wrong gain for a silent song: %f", gain)
+	}
+
+	//the song is not needed anymore after it was analyzed, the value is read from the stored db
+	err = os.Remove(songPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = mp3.InitLoudnessDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gain, err = mp3.GetSongGain(songPath)
+	if err != nil {
+		t.Fatalf("Loudness was not stored in the db: %s", err)
+	}
+	if gain != 0 {
+		t.Errorf("Wrong stored gain for a silent song: %f", gain)
+	}
+}
+
+func TestLoudnessBackgroundAnalysis(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goparty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer mp3.SetLoudnessNormalization(false)
+
+	songDir := filepath.Join(dir, "music") + string(os.PathSeparator)
+	err = os.Mkdir(songDir, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.mp3", "b.mp3", "c.mp3"} {
+		err = writeSilentMP3(filepath.Join(songDir, name), 20)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dbPath := filepath.Join(dir, "loudness.json")
+	err = mp3.InitLoudnessDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = mp3.InitializeSongDBFromMemory(songDir, songDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//added songs are analyzed in the background, stopping waits for the running analyses, so all songs are stored afterwards
+	mp3.SetLoudnessNormalization(true)
+	for _, name := range []string{"a.mp3", "b.mp3", "c.mp3"} {
+		mp3.AddSongToDB(songDir, name)
+	}
+	mp3.StopLoudnessAnalysis()
+	content, err := ioutil.ReadFile(dbPath)
+	if err != nil {
+		t.Fatalf("The loudness db was not written: %s", err)
+	}
+	for _, name := range []string{"a.mp3", "b.mp3", "c.mp3"} {
+		if strings.Contains(string(content), name) == false {
+			t.Errorf("%s is missing in the loudness db: %s", name, content)
+		}
+	}
+
+	//no analysis is started after stopping, so the db is not written again after the folder was removed
+	mp3.AddSongToDB(songDir, "d.mp3")
+}
@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/procrastimax/goparty/server"
+)
+
+func TestParseOptions(t *testing.T) {
+	os.Setenv("GOPARTY_LISTEN", ":9000")
+	os.Setenv("GOPARTY_MAX_QUEUE_LENGTH", "5")
+	os.Setenv("GOPARTY_DUPLICATE_TITLES", "true")
+	os.Setenv("GOPARTY_LOG_MAX_FILES", "4")
+	defer os.Unsetenv("GOPARTY_LISTEN")
+	defer os.Unsetenv("GOPARTY_MAX_QUEUE_LENGTH")
+	defer os.Unsetenv("GOPARTY_DUPLICATE_TITLES")
+	defer os.Unsetenv("GOPARTY_LOG_MAX_FILES")
+
+	options, err := server.ParseOptions([]string{"--config", "party.json", "--max-queue-length", "7", "--all-user-admin", "--music-path=/music/", "--log-max-size-mb", "20"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if options.ConfigPath != "party.json" || options.ListenAddr != ":9000" || options.UserNameFile != "usernames.txt" || options.TemplateDir != "" {
+		t.Errorf("Wrong options: %+v", options)
+	}
+
+	//flags take precedence over environment variables
+	expected := map[string]string{
+		"maxQueueLength":  "7",
+		"duplicateTitles": "true",
+		"allUserAdmin":    "true",
+		"musicPath":       "/music/",
+		"logMaxFiles":     "4",
+		//capital letters at the end are one word
+		"logMaxSizeMB": "20",
+	}
+	for name, value := range expected {
+		if options.Overrides[name] != value {
+			t.Errorf("Expected override %s=%s, got %q", name, value, options.Overrides[name])
+		}
+	}
+	if len(options.Overrides) != len(expected) {
+		t.Errorf("Unexpected overrides: %v", options.Overrides)
+	}
+
+	_, err = server.ParseOptions([]string{"--unknown-flag"})
+	if err == nil {
+		t.Error("Expected an error for an unknown flag")
+	}
+
+	os.Setenv("GOPARTY_LOG_MAX_SIZE_MB", "30")
+	defer os.Unsetenv("GOPARTY_LOG_MAX_SIZE_MB")
+	options, err = server.ParseOptions([]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if options.Overrides["logMaxSizeMB"] != "30" {
+		t.Errorf("Expected override logMaxSizeMB=30 from the environment, got %q", options.Overrides["logMaxSizeMB"])
+	}
+}
+
+func TestPrintConfigMasksPassword(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goparty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.json")
+	err = ioutil.WriteFile(path, []byte(`{"downloadPath": "/music/yt/", "musicPath": "/music/", "icecastPassword": "hackme"}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	err = server.PrintConfig(server.Options{ConfigPath: path})
+	os.Stdout = stdout
+	writer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(out), "hackme") || strings.Contains(string(out), `"icecastPassword": "********"`) == false {
+		t.Errorf("Icecast password is not masked: %s", out)
+	}
+}
@@ -0,0 +1,138 @@
+package tests
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/procrastimax/goparty/mp3"
+)
+
+func TestPlayerStateMachine(t *testing.T) {
+	if mp3.GetPlayerState() != mp3.Stopped {
+		t.Fatal("Player should be stopped initially")
+	}
+
+	if mp3.Pause() == nil {
+		t.Error("Pausing a stopped player should fail")
+	}
+
+	if mp3.Resume() == nil {
+		t.Error("Resuming a stopped player should fail")
+	}
+
+	err := mp3.SetOutput(mp3.NewNullOutput())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mp3.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mp3.Stop(true)
+
+	if mp3.SetOutput(mp3.NewNullOutput()) == nil {
+		t.Error("Changing the output of a running player should fail")
+	}
+
+	steps := []struct {
+		name     string
+		action   func() error
+		expected mp3.PlayerState
+	}{
+		{"start again", mp3.Start, mp3.Playing},
+		{"pause", mp3.Pause, mp3.Paused},
+		{"pause again", mp3.Pause, mp3.Paused},
+		{"start paused", mp3.Start, mp3.Playing},
+		{"pause", mp3.Pause, mp3.Paused},
+		{"resume", mp3.Resume, mp3.Playing},
+		{"stop", func() error { mp3.Stop(false); return nil }, mp3.Stopped},
+		{"restart", mp3.Start, mp3.Playing},
+	}
+
+	for _, step := range steps {
+		err := step.action()
+		if err != nil {
+			t.Errorf("%s: %s", step.name, err)
+		}
+		if mp3.GetPlayerState() != step.expected {
+			t.Errorf("%s: expected state %s, got %s", step.name, step.expected, mp3.GetPlayerState())
+		}
+	}
+}
+
+func TestPlayerStopQueue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goparty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	songDir := dir + string(os.PathSeparator)
+	for _, name := range []string{"first.mp3", "second.mp3"} {
+		err = ioutil.WriteFile(filepath.Join(dir, name), []byte{}, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = mp3.AddMP3ToMusicQueue(songDir, name, "127.0.0.1", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mp3.Stop(false)
+	if len(mp3.GetCurrentPlaylist()) != 2 {
+		t.Errorf("Stopping without clearing should keep the queue, got %d songs", len(mp3.GetCurrentPlaylist()))
+	}
+
+	mp3.Stop(true)
+	if len(mp3.GetCurrentPlaylist()) != 0 {
+		t.Errorf("Stopping with clearing should empty the queue, got %d songs", len(mp3.GetCurrentPlaylist()))
+	}
+}
+
+func TestWAVOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goparty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	recordPath := filepath.Join(dir, "party.wav")
+	err = mp3.SetOutput(mp3.NewWAVOutput(recordPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mp3.SetOutput(mp3.NewNullOutput())
+
+	err = mp3.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(350 * time.Millisecond)
+	mp3.Stop(true)
+
+	file, err := ioutil.ReadFile(recordPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(file) <= 44 || string(file[0:4]) != "RIFF" || string(file[8:12]) != "WAVE" {
+		t.Fatalf("Recorded file is not a wav file with samples, size %d", len(file))
+	}
+
+	dataSize := binary.LittleEndian.Uint32(file[40:44])
+	if int(dataSize) != len(file)-44 {
+		t.Errorf("Data size in header is %d, but the file contains %d bytes of samples", dataSize, len(file)-44)
+	}
+
+	//the null and wav outputs pull the samples in real time
+	if dataSize%4 != 0 || dataSize < 44100*4/10 {
+		t.Errorf("Expected at least 100ms of 16 bit stereo samples, got %d bytes", dataSize)
+	}
+}
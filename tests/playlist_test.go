@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/procrastimax/goparty/mp3"
+)
+
+func TestParsePLS(t *testing.T) {
+	content := "[playlist]\nNumberOfEntries=2\nFile2=/music/second.mp3\nTitle2=Second\nFile1=first.mp3\nTitle1=First\nVersion=2\n"
+	entries, err := mp3.ParsePlaylist(strings.NewReader(content), "party.PLS", "/home/party")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0] != "/home/party/first.mp3" || entries[1] != "/music/second.mp3" {
+		t.Errorf("Wrong playlist entries: %v", entries)
+	}
+
+	_, err = mp3.ParsePlaylist(strings.NewReader(content), "party.txt", "")
+	if err == nil {
+		t.Error("Expected an error for an unknown playlist format")
+	}
+}
+
+func TestImportExportPlaylist(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goparty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	songDir := dir + string(os.PathSeparator)
+	for _, name := range []string{"first.mp3", "second.mp3"} {
+		err = writeSilentMP3(filepath.Join(dir, name), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = mp3.InitializeSongDBFromMemory(songDir, songDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//the playlist was created on another machine, so the songs are matched by their filename
+	content := "#EXTM3U\n#EXTINF:100,Second\nC:\\Users\\host\\Music\\Second.mp3\n#EXTINF:100,Missing\n/music/missing.mp3\n" + songDir + "first.mp3\n"
+	entries, err := mp3.ParsePlaylist(strings.NewReader(content), "party.m3u8", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	added, missing := mp3.ImportPlaylist(entries, "127.0.0.1")
+	defer mp3.Stop(true)
+	if added != 2 {
+		t.Errorf("Expected 2 imported songs, got %d", added)
+	}
+	if len(missing) != 1 || missing[0] != "/music/missing.mp3" {
+		t.Errorf("Wrong missing songs: %v", missing)
+	}
+
+	var m3u bytes.Buffer
+	err = mp3.WriteM3U(&m3u, mp3.GetQueueEntries())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "#EXTM3U\n#EXTINF:-1,second\n" + songDir + "second.mp3\n#EXTINF:-1,first\n" + songDir + "first.mp3\n"
+	if m3u.String() != expected {
+		t.Errorf("Wrong queue export:\n%s", m3u.String())
+	}
+
+	if songs := mp3.GetSongDBEntries("", "FIRST"); len(songs) != 1 || songs[0].Path != songDir+"first.mp3" {
+		t.Errorf("Wrong song database selection: %v", songs)
+	}
+}
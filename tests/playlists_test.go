@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/procrastimax/goparty/playlists"
+)
+
+func TestSavedPlaylists(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goparty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "playlists.json")
+	err = playlists.Init(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer playlists.Init("")
+
+	err = playlists.Save("warm-up", []string{"/music/first.mp3", " ", "https://youtu.be/abcdefghijk "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = playlists.Add("after midnight", "/music/second.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = playlists.Save(" ", nil)
+	if err == nil {
+		t.Error("Expected an error for a playlist without a name")
+	}
+
+	entries, ok := playlists.Get("warm-up")
+	if ok == false || len(entries) != 2 || entries[1] != "https://youtu.be/abcdefghijk" {
+		t.Errorf("Wrong playlist entries: %v", entries)
+	}
+
+	err = playlists.Rename("warm-up", "after midnight")
+	if err == nil {
+		t.Error("Expected an error when renaming to an existing playlist")
+	}
+	err = playlists.Rename("warm-up", "opening")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//the playlists need to survive a restart
+	err = playlists.Init(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := playlists.GetNames()
+	if len(names) != 2 || names[0] != "after midnight" || names[1] != "opening" {
+		t.Errorf("Wrong stored playlists: %v", names)
+	}
+
+	err = playlists.Delete("opening")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := playlists.Get("opening"); ok {
+		t.Error("Deleted playlist still exists")
+	}
+	if playlists.Delete("opening") == nil {
+		t.Error("Expected an error when deleting a missing playlist")
+	}
+}
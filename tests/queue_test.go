@@ -0,0 +1,150 @@
+package tests
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/procrastimax/goparty/mp3"
+)
+
+//queueNames returns the names of all songs in the queue, pinned songs are marked with a star
+func queueNames() string {
+	names := make([]string, 0)
+	for _, song := range mp3.GetCurrentPlaylist() {
+		if song.Pinned {
+			names = append(names, song.SongName+"*")
+		} else {
+			names = append(names, song.SongName)
+		}
+	}
+	return strings.Join(names, " ")
+}
+
+func TestQueueEditing(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goparty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer mp3.Stop(true)
+
+	songDir := dir + string(os.PathSeparator)
+	for i, name := range []string{"a", "b", "c", "d", "e"} {
+		err = writeSilentMP3(filepath.Join(dir, name+".mp3"), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = mp3.AddMP3ToMusicQueue(songDir, name+".mp3", "10.0.44."+string(rune('1'+i)), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if names := queueNames(); names != "a b c d e" {
+		t.Fatalf("Wrong queue: %s", names)
+	}
+
+	err = mp3.PinSong(3, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := queueNames(); names != "a d* b c e" {
+		t.Errorf("Pinned song is not played next: %s", names)
+	}
+
+	//songs cannot be moved in front of pinned songs
+	err = mp3.MoveSong(4, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := queueNames(); names != "a d* e b c" {
+		t.Errorf("Wrong queue after moving: %s", names)
+	}
+
+	err = mp3.RemoveSong(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := queueNames(); names != "a d* e c" {
+		t.Errorf("Wrong queue after removing: %s", names)
+	}
+	if mp3.RemoveSong(10) == nil || mp3.MoveSong(0, 2) == nil {
+		t.Error("Expected an error for invalid positions")
+	}
+
+	//no song can be moved in front of the currently playing song, the queue stays unchanged
+	for _, position := range []int{0, -1} {
+		if mp3.MoveSong(1, position) == nil || mp3.MoveSong(2, position) == nil {
+			t.Errorf("Expected an error for moving to position %d", position)
+		}
+		if names := queueNames(); names != "a d* e c" {
+			t.Errorf("Wrong queue after moving to position %d: %s", position, names)
+		}
+	}
+
+	//songs moved behind the end of the queue are moved to the end
+	err = mp3.MoveSong(2, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := queueNames(); names != "a d* c e" {
+		t.Errorf("Wrong queue after moving behind the end: %s", names)
+	}
+
+	err = mp3.PinSong(1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := queueNames(); names != "a d c e" {
+		t.Errorf("Wrong queue after unpinning: %s", names)
+	}
+}
+
+func TestQueuePreload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goparty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	songDir := dir + string(os.PathSeparator)
+	err = ioutil.WriteFile(filepath.Join(dir, "broken.mp3"), []byte{}, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = writeSilentMP3(filepath.Join(dir, "good.mp3"), 400)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mp3.SetOutput(mp3.NewNullOutput())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = mp3.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mp3.Stop(true)
+
+	for _, name := range []string{"broken.mp3", "good.mp3"} {
+		err = mp3.AddMP3ToMusicQueue(songDir, name, "10.0.45.1", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	//the broken song is skipped and the next song is loaded by the preloading, not by the output
+	if waitFor(3*time.Second, func() bool { return queueNames() == "good" }) == false {
+		t.Fatalf("Broken song was not skipped: %s", queueNames())
+	}
+	if waitFor(3*time.Second, func() bool {
+		position, _, ok := mp3.GetPosition()
+		return ok && position > 0
+	}) == false {
+		t.Error("Next song is not playing")
+	}
+}
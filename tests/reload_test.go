@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/procrastimax/goparty/mp3"
+	"github.com/procrastimax/goparty/youtube"
+)
+
+//TestLiveReloadSettings changes the settings of a config reload while the queue plays, run it with -race
+func TestLiveReloadSettings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goparty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	//the songs are analyzed in the background while the normalization is enabled, the analyses are finished before the folder is removed
+	defer mp3.StopLoudnessAnalysis()
+	err = mp3.InitLoudnessDB(filepath.Join(dir, "loudness.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	songDir := dir + string(os.PathSeparator)
+	for _, name := range []string{"a.mp3", "b.mp3", "c.mp3"} {
+		err = writeSilentMP3(filepath.Join(dir, name), 20)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = mp3.SetOutput(mp3.NewNullOutput())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = mp3.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mp3.Stop(true)
+	defer mp3.SetLoudnessNormalization(false)
+	defer mp3.SetNeededUpvoteCount(1)
+	defer mp3.SetDuplicateDetection(0, false)
+	defer youtube.SetLimits(youtube.Limits{}, func(userIP string) bool { return true })
+
+	done := make(chan bool)
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	//the reload applies the settings over and over again
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			mp3.SetLoudnessNormalization(i%2 == 0)
+			mp3.SetNeededUpvoteCount(1 + i%3)
+			mp3.SetDuplicateDetection(time.Duration(i%2)*time.Minute, i%2 == 0)
+			mp3.SetMaxStreamListeners(i % 5)
+			youtube.SetLimits(youtube.Limits{MaxDuration: time.Duration(i%10) * time.Minute}, func(userIP string) bool { return true })
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	//the guests add and upvote songs meanwhile
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			name := []string{"a.mp3", "b.mp3", "c.mp3"}[i%3]
+			if _, _, ok := mp3.UpvoteDuplicate(mp3.SongKey{FilePath: songDir + name}, "10.0.45.9"); ok == false {
+				mp3.AddMP3ToMusicQueue(songDir, name, "10.0.45.8", false)
+			}
+			mp3.GetRecentlyPlayed(mp3.SongKey{SongName: name})
+			mp3.UpvoteSong(1, "10.0.45.7")
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	time.Sleep(500 * time.Millisecond)
+	close(done)
+	wg.Wait()
+}
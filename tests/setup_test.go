@@ -0,0 +1,70 @@
+package tests
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/procrastimax/goparty/server"
+)
+
+func TestSetupWizard(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goparty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	musicDir := filepath.Join(dir, "music")
+	err = os.Mkdir(musicDir, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//a missing music folder is asked again, invalid answers too
+	answers := []string{
+		filepath.Join(dir, "missing"),
+		musicDir,
+		"",
+		"abc",
+		"3",
+		"",
+		"maybe",
+		"y",
+		"n",
+	}
+	path := filepath.Join(dir, "config", "config.json")
+	var out strings.Builder
+	err = server.RunSetupWizard(path, strings.NewReader(strings.Join(answers, "\n")+"\n"), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := server.ReadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//the paths are normalized with a trailing separator
+	downloadDir := filepath.Join(musicDir, "goparty") + string(os.PathSeparator)
+	if cfg.MusicPath != musicDir+string(os.PathSeparator) || cfg.DownloadPath != downloadDir {
+		t.Errorf("Wrong paths: %s %s", cfg.MusicPath, cfg.DownloadPath)
+	}
+	if _, err := os.Stat(downloadDir); err != nil {
+		t.Errorf("Download folder was not created: %s", err)
+	}
+	if cfg.UpvotesNeededForRanking != 3 || cfg.MaxSongDuration != 10 || cfg.AllUserAdmin == false || cfg.Output != "null" {
+		t.Errorf("Wrong settings: %+v", cfg)
+	}
+	if strings.Contains(out.String(), "does not exist") == false {
+		t.Error("The missing music folder was not reported")
+	}
+
+	//the wizard stops when the input ends
+	err = server.RunSetupWizard(path, strings.NewReader(musicDir+"\n"), &out)
+	if err == nil {
+		t.Error("Expected an error for an unfinished setup")
+	}
+}
@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/procrastimax/goparty/mp3"
+)
+
+func TestSaveAndRestoreQueue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goparty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer mp3.Stop(true)
+
+	songDir := dir + string(os.PathSeparator)
+	for i, name := range []string{"a", "b", "c"} {
+		err = writeSilentMP3(filepath.Join(dir, name+".mp3"), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = mp3.AddMP3ToMusicQueue(songDir, name+".mp3", "10.0.49."+string(rune('1'+i)), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = mp3.PinSong(2, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mp3.UpvoteSong(1, "10.0.49.9")
+
+	path := filepath.Join(dir, "queue.json")
+	err = mp3.SaveQueue(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mp3.Stop(true)
+
+	//songs whose files were deleted are not restored
+	err = os.Remove(filepath.Join(dir, "b.mp3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := mp3.RestoreQueue(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 restored songs, got %d", count)
+	}
+	if names := queueNames(); names != "a c*" {
+		t.Errorf("Wrong restored queue: %s", names)
+	}
+	if songs := mp3.GetCurrentPlaylist(); len(songs) == 2 && songs[1].GetUpvotesCount() != 1 {
+		t.Error("The upvotes were not restored")
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) == false {
+		t.Error("The queue file was not removed after restoring")
+	}
+
+	//an empty queue removes the saved queue
+	mp3.Stop(true)
+	err = ioutil.WriteFile(path, []byte("[]"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = mp3.SaveQueue(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) == false {
+		t.Error("The queue file of an empty queue was not removed")
+	}
+}
@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/procrastimax/goparty/clients"
+	"github.com/procrastimax/goparty/stats"
+)
+
+func TestStatsSummary(t *testing.T) {
+	stats.Reset()
+	defer stats.Reset()
+
+	stats.AddSong("192.168.0.2")
+	stats.AddSong("192.168.0.3")
+	stats.AddSong("192.168.0.3")
+	stats.AddDownload("192.168.0.3", false)
+	stats.AddDownload("192.168.0.3", true)
+
+	stats.AddPlay("192.168.0.3", 3*time.Minute, false, false)
+	stats.AddPlay("192.168.0.3", 30*time.Second, true, true)
+	stats.AddPlay("192.168.0.2", time.Minute, false, true)
+
+	stats.AddVote("192.168.0.2", "Song A", "192.168.0.3")
+	stats.AddVote("192.168.0.4", "Song A", "192.168.0.3")
+	stats.AddVote("192.168.0.4", "Song B", "192.168.0.2")
+
+	summary := stats.GetSummary()
+
+	if summary.PlayTime != (4*time.Minute + 30*time.Second).Seconds() {
+		t.Errorf("Wrong play time %f", summary.PlayTime)
+	}
+	if summary.PlayedSongs != 3 || summary.SkippedSongs != 1 {
+		t.Errorf("Wrong played/ skipped songs %d/%d", summary.PlayedSongs, summary.SkippedSongs)
+	}
+	if summary.YoutubePlays != 1 || summary.OfflinePlays != 2 {
+		t.Errorf("Wrong youtube/ offline plays %d/%d", summary.YoutubePlays, summary.OfflinePlays)
+	}
+	if summary.Downloads != 1 || summary.CachedSongs != 1 || summary.Votes != 3 {
+		t.Errorf("Wrong downloads %d, cached songs %d or votes %d", summary.Downloads, summary.CachedSongs, summary.Votes)
+	}
+
+	top := summary.Contributors[0]
+	if top.UserIP != "192.168.0.3" || top.AddedSongs != 2 || top.SkipRate != 0.5 {
+		t.Errorf("Wrong top contributor %+v", top)
+	}
+
+	if len(summary.UpvotedSongs) != 2 || summary.UpvotedSongs[0].SongName != "Song A" || summary.UpvotedSongs[0].Upvotes != 2 {
+		t.Errorf("Wrong most upvoted songs %+v", summary.UpvotedSongs)
+	}
+}
+
+func TestStatsHouseUser(t *testing.T) {
+	stats.Reset()
+	defer stats.Reset()
+
+	stats.AddSong(clients.HouseUserIP)
+	stats.AddSong(clients.HouseUserIP)
+	stats.AddPlay(clients.HouseUserIP, time.Minute, false, true)
+	stats.AddSong("192.168.0.2")
+
+	summary := stats.GetSummary()
+	if len(summary.Contributors) != 1 || summary.Contributors[0].UserIP != "192.168.0.2" {
+		t.Errorf("House user should not be a contributor: %+v", summary.Contributors)
+	}
+	//the played house songs still count for the party
+	if summary.PlayedSongs != 1 {
+		t.Errorf("Wrong played songs %d", summary.PlayedSongs)
+	}
+
+	clients.AddSongPlaylist(clients.HouseUserIP)
+	defer clients.SongDonePlaying(clients.HouseUserIP)
+	if _, ok := clients.GetUsers()[clients.HouseUserIP]; ok {
+		t.Error("House user should not be listed with the users")
+	}
+}
@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/procrastimax/goparty/mp3"
+)
+
+func TestStreamListener(t *testing.T) {
+	mp3.SetMaxStreamListeners(0)
+	if _, err := mp3.AddStreamListener(); err == nil {
+		t.Error("Adding a listener to a disabled stream should fail")
+	}
+
+	mp3.SetMaxStreamListeners(1)
+	defer mp3.SetMaxStreamListeners(0)
+
+	listener, err := mp3.AddStreamListener()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mp3.RemoveStreamListener(listener)
+
+	if _, err := mp3.AddStreamListener(); err == nil {
+		t.Error("Adding more listeners than allowed should fail")
+	}
+
+	err = mp3.SetOutput(mp3.NewNullOutput())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = mp3.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mp3.Stop(true)
+
+	select {
+	case chunk := <-listener.C:
+		if len(chunk) == 0 || len(chunk)%4 != 0 {
+			t.Errorf("Expected 16 bit stereo samples, got %d bytes", len(chunk))
+		}
+	case <-time.After(time.Second):
+		t.Error("Listener did not receive any samples")
+	}
+
+	mp3.RemoveStreamListener(listener)
+	if mp3.GetStreamListenerCount() != 0 {
+		t.Error("Listener was not removed")
+	}
+	//the channel gets closed when removing the listener, so draining it ends
+	for range listener.C {
+	}
+}
@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/procrastimax/goparty/mp3"
+)
+
+func TestVolumeToGain(t *testing.T) {
+	//the amplitude is 10^gain, it is the squared volume fraction
+	expected := map[int]float64{
+		100: 1,
+		50:  0.25,
+		10:  0.01,
+	}
+	for percent, amplitude := range expected {
+		actual := math.Pow(10, mp3.VolumeToGain(percent))
+		if math.Abs(actual-amplitude) > 0.0001 {
+			t.Errorf("Wrong amplitude for %d%%: %f, expected %f", percent, actual, amplitude)
+		}
+	}
+
+	//a gain of 0 is the full loudness, 0% is played silent instead
+	if mp3.VolumeToGain(0) != 0 {
+		t.Errorf("Wrong gain for 0%%: %f", mp3.VolumeToGain(0))
+	}
+}
+
+func TestVolumeSettings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goparty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	settingsPath := filepath.Join(dir, "player.json")
+	err = mp3.InitPlayerSettings(settingsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mp3.InitPlayerSettings("")
+	defer mp3.SetVolume(mp3.MaxVolume)
+
+	mp3.SetVolume(150)
+	if mp3.GetVolume() != mp3.MaxVolume {
+		t.Errorf("Volume was not limited: %d", mp3.GetVolume())
+	}
+
+	//a volume of 0 silences the music, but it does not mute it
+	mp3.SetVolume(0)
+	if mp3.GetVolume() != 0 || mp3.IsMuted() {
+		t.Errorf("Wrong state for 0%%: volume %d muted %t", mp3.GetVolume(), mp3.IsMuted())
+	}
+	mp3.SetMute(true)
+	mp3.SetVolume(40)
+	if mp3.IsMuted() == false {
+		t.Error("Changing the volume should not unmute the music")
+	}
+	mp3.SetMute(false)
+
+	settings, err := ioutil.ReadFile(settingsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(settings), `"volume": 40`) == false {
+		t.Errorf("Volume was not persisted: %s", settings)
+	}
+}
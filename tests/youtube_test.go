@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/procrastimax/goparty/clients"
+	"github.com/procrastimax/goparty/youtube"
+)
+
+//fakeYoutubeDL prints the metadata of a short video or a ten hour video, depending on the link
+//every metadata fetch is logged to fetches.log next to the script, downloads take two seconds and create no file
+const fakeYoutubeDL = `#!/bin/sh
+for last; do :; done
+case "$*" in
+*--dump-json*) echo "$last" >> "$(dirname "$0")/fetches.log"; sleep 0.3 ;;
+*) sleep 2; exit 0 ;;
+esac
+case "$last" in
+*longvideo*) echo '{"id":"longvideo01","title":"Ten Hour Loop","channel":"Loops","duration":36000,"age_limit":0}' ;;
+*) echo '{"id":"shortvideo1","title":"Short Song","channel":"Songs","duration":200,"age_limit":0}' ;;
+esac
+`
+
+func TestVideoMetadata(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake youtube-dl is a shell script")
+	}
+
+	dir, err := ioutil.TempDir("", "goparty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "youtube-dl"), []byte(fakeYoutubeDL), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+path)
+	defer os.Setenv("PATH", path)
+	youtube.MustExistYoutubeDL()
+
+	youtube.SetLimits(youtube.Limits{MaxDuration: 10 * time.Minute}, func(userIP string) bool { return true })
+	defer youtube.SetLimits(youtube.Limits{}, func(userIP string) bool { return true })
+
+	//the download worker checks the first video while its metadata is prefetched
+	youtube.StartDownloadWorker(filepath.Join(dir, "downloads"), func(dataDir, filename, userIP string, newSong bool) error {
+		return nil
+	})
+
+	ip := "10.0.42.1"
+	err = youtube.Add("https://youtu.be/shortvideo1", ip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = youtube.Add("https://youtu.be/longvideo01", ip)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//the metadata is fetched in the background, the long video gets removed from the download queue
+	ok := waitFor(3*time.Second, func() bool {
+		pending := youtube.GetPendingDownloads()
+		return len(pending) == 1 && pending[0].Title == "Short Song"
+	})
+	if ok == false {
+		t.Fatalf("Wrong pending downloads: %+v", youtube.GetPendingDownloads())
+	}
+
+	pending := youtube.GetPendingDownloads()[0]
+	if pending.Channel != "Songs" || pending.Duration != 200*time.Second || pending.Downloading == false {
+		t.Errorf("Wrong metadata of the pending download: %+v", pending)
+	}
+
+	notices := clients.PopNotices(ip)
+	if len(notices) != 1 {
+		t.Errorf("Expected a notice for the rejected video, got %v", notices)
+	}
+
+	//the prefetch and the download worker share the fetch of the first video
+	fetches, err := ioutil.ReadFile(filepath.Join(dir, "fetches.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := strings.Count(string(fetches), "shortvideo1"); count != 1 {
+		t.Errorf("Metadata of the first video was fetched %d times", count)
+	}
+
+	//the fake download creates no file, the video is done afterwards
+	if waitFor(5*time.Second, func() bool { return len(youtube.GetPendingDownloads()) == 0 }) == false {
+		t.Errorf("Download did not finish: %+v", youtube.GetPendingDownloads())
+	}
+}
@@ -18,15 +18,19 @@ import (
 	"bytes"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/procrastimax/goparty/blocklist"
 	"github.com/procrastimax/goparty/clients"
+	"github.com/procrastimax/goparty/logging"
 	"github.com/procrastimax/goparty/mp3"
+	"github.com/procrastimax/goparty/stats"
 )
 
 var (
@@ -35,11 +39,23 @@ var (
 	quitCh       = make(chan bool)
 	queue        downloadQueue
 	youtubeDlDir string
+
+	//workerMutex guards the running youtube-dl process and the stopping flag
+	workerMutex sync.Mutex
+	runningCmd  *exec.Cmd
+	stopping    bool
+	//workerDone is closed when the download worker stopped, it is nil when the worker was not started
+	workerDone chan bool
+
+	//errCanceled is returned for a download which was canceled because the worker stopped
+	errCanceled = fmt.Errorf("the download was canceled")
 )
 
 type downloadEntity struct {
 	mp3.Song
 	url string
+	//canceled is set when the user was kicked while the video is downloading, the downloaded song is not added to the queue
+	canceled bool
 }
 
 func (d downloadEntity) String() string {
@@ -53,8 +69,20 @@ type downloadQueue struct {
 }
 
 //Add adds an url to the worker list of urls
-func Add(url string, userIP string) {
+//returns an error explaining why the video was rejected when the video is blocked
+func Add(url string, userIP string) error {
+	if videoID, err := mp3.GetYoutubeVideoID(url); err == nil {
+		err = blocklist.CheckVideo(videoID)
+		if err != nil {
+			return err
+		}
+	}
+
 	queue.Lock()
+	if isStopping() {
+		queue.Unlock()
+		return fmt.Errorf("the server is shutting down")
+	}
 
 	clients.AddSongDownload(userIP)
 
@@ -66,6 +94,7 @@ func Add(url string, userIP string) {
 	song := downloadEntity{
 		mp3.Song{UserIP: userIP, SongCount: clients.GetUserAddedSongs(userIP).DownloadingSongs},
 		url,
+		false,
 	}
 
 	if len(queue.songs) <= 1 {
@@ -93,11 +122,119 @@ func Add(url string, userIP string) {
 	}
 
 	queue.Unlock()
+
+	go prefetchVideoInfo(url, userIP)
+	return nil
+}
+
+//remove removes a waiting video of the user from the download queue, the currently downloading video cannot be removed
+//returns false when the video is not waiting in the queue
+func remove(url string, userIP string) bool {
+	queue.Lock()
+	defer queue.Unlock()
+
+	for i := 1; i < len(queue.songs); i++ {
+		if queue.songs[i].url == url && queue.songs[i].UserIP == userIP {
+			queue.songs = append(queue.songs[:i], queue.songs[i+1:]...)
+			clients.SongDoneDownloading(userIP)
+
+			//the following songs of the user move forward
+			for j := i; j < len(queue.songs); j++ {
+				if queue.songs[j].UserIP == userIP && queue.songs[j].SongCount > 0 {
+					queue.songs[j].SongCount--
+				}
+			}
+			return true
+		}
+	}
+	return false
+}
+
+//RemoveUserDownloads removes all waiting videos of the user from the download queue
+//the currently downloading video of the user is finished, but it is only added to the song database and not to the queue
+//returns the count of removed videos
+func RemoveUserDownloads(userIP string) int {
+	queue.Lock()
+	defer queue.Unlock()
+
+	removed := 0
+	songs := make([]downloadEntity, 0, len(queue.songs))
+	for i, song := range queue.songs {
+		if i == 0 && song.UserIP == userIP && song.canceled == false {
+			song.canceled = true
+			removed++
+		}
+		if i == 0 || song.UserIP != userIP {
+			songs = append(songs, song)
+			continue
+		}
+		clients.SongDoneDownloading(userIP)
+		forgetVideoInfo(song.url)
+		removed++
+	}
+	queue.songs = songs
+
+	if removed > 0 {
+		logging.Info("Removed the downloads of the user", "ip", userIP, "removed", removed)
+	}
+	return removed
 }
 
-//ExitDownloadWorker quits the donloading worker loop by sending a value on the quit channel
-func ExitDownloadWorker() {
-	quitCh <- true
+//GetPendingCount returns the count of songs which are downloading or waiting to be downloaded
+func GetPendingCount() int {
+	queue.Lock()
+	defer queue.Unlock()
+	return len(queue.songs)
+}
+
+//IsPending returns true when the youtube video with the given id is downloading or waiting to be downloaded
+func IsPending(videoID string) bool {
+	queue.Lock()
+	defer queue.Unlock()
+	for _, song := range queue.songs {
+		if id, err := mp3.GetYoutubeVideoID(song.url); err == nil && id == videoID {
+			return true
+		}
+	}
+	return false
+}
+
+//isStopping returns true when the download worker is stopping, no new downloads are started then
+func isStopping() bool {
+	workerMutex.Lock()
+	defer workerMutex.Unlock()
+	return stopping
+}
+
+//StopDownloadWorker stops the download worker, the running download gets the given time to finish
+//afterwards it is canceled and its partial files are removed, the canceled and the waiting videos stay in the download queue
+func StopDownloadWorker(timeout time.Duration) {
+	workerMutex.Lock()
+	if stopping || workerDone == nil {
+		stopping = true
+		workerMutex.Unlock()
+		return
+	}
+	stopping = true
+	workerMutex.Unlock()
+	close(quitCh)
+
+	select {
+	case <-workerDone:
+		return
+	case <-time.After(timeout):
+	}
+
+	workerMutex.Lock()
+	if runningCmd != nil && runningCmd.Process != nil {
+		logging.Info("Canceling the running download")
+		err := killCommand(runningCmd)
+		if err != nil {
+			logging.Error("Could not cancel the running download", "err", err)
+		}
+	}
+	workerMutex.Unlock()
+	<-workerDone
 }
 
 //done removes the first element of the queue when done, also decreases the addedcount of the user by 1 for all added songs
@@ -112,14 +249,34 @@ func done(userIP string) {
 			}
 		}
 	}
+	forgetVideoInfo(queue.songs[0].url)
 	queue.songs = queue.songs[1:]
 
-	if len(queue.songs) != 0 {
+	if len(queue.songs) != 0 && isStopping() == false {
 		jobCh <- queue.songs[0]
 	}
 	queue.Unlock()
 }
 
+//isCanceled returns true when the currently downloading video was canceled by kicking its user
+func isCanceled(url string) bool {
+	queue.Lock()
+	defer queue.Unlock()
+	return len(queue.songs) > 0 && queue.songs[0].url == url && queue.songs[0].canceled
+}
+
+//passDownloadedSong passes the downloaded song to the callback, songs of canceled downloads are only added to the song database
+func passDownloadedSong(job *downloadEntity, downloadDir, filename string, newSong bool, mp3AddCallback func(songDir, filename, userIP string, newSong bool) error) error {
+	if isCanceled(job.url) {
+		logging.Info("Not adding the song of the kicked user", "url", job.url, "ip", job.UserIP, "file", filename)
+		if newSong {
+			mp3.AddSongToDB(downloadDir, filename)
+		}
+		return nil
+	}
+	return mp3AddCallback(downloadDir, filename, job.UserIP, newSong)
+}
+
 //MustExistYoutubeDL is a helper function, which panics when no youtube-dl exist
 func MustExistYoutubeDL() {
 	dir, err := exec.LookPath("youtube-dl")
@@ -131,37 +288,61 @@ func MustExistYoutubeDL() {
 
 //StartDownloadWorker starts downlading
 func StartDownloadWorker(downloadDir string, mp3AddCallback func(dataDir, filename, userIP string, newSong bool) error) {
-	fmt.Println("Started YT-Download Worker!")
+	logging.Info("Started the download worker")
 	var err error
 	var existsFilename string
+	workerMutex.Lock()
+	workerDone = make(chan bool)
+	workerMutex.Unlock()
 	go func() {
+		defer close(workerDone)
 		for {
 			select {
 			case <-quitCh:
-				fmt.Println("Stopping Download Worker")
+				logging.Info("Stopped the download worker")
 				return
 
 			case job := <-jobCh:
+				//a job can still be buffered when the worker stops
+				if isStopping() {
+					logging.Info("Stopped the download worker")
+					return
+				}
+
 				existsFilename, err = checkFileExist(downloadDir, job.url)
 
 				if err != nil {
-					log.Fatalln("StartDowloadWorker:", err)
+					logging.Fatal("StartDownloadWorker: could not check the download folder", "err", err)
 				}
 
 				// when the file already we dont need to download it
 				if len(existsFilename) != 0 {
-					fmt.Println("Song already exists, not downloading again.")
-					err = mp3AddCallback(downloadDir, existsFilename, job.UserIP, false)
+					logging.Info("Song already exists, not downloading again", "url", job.url, "file", existsFilename)
+					stats.AddDownload(job.UserIP, true)
+					err = passDownloadedSong(&job, downloadDir, existsFilename, false, mp3AddCallback)
 					if err != nil {
-						log.Fatalln(err)
+						logging.Fatal("Could not add the downloaded song", "file", existsFilename, "err", err)
 					}
 					done(job.UserIP)
 					break
 				}
 
-				err = downloadYoutubeVideoAsMP3(&job, downloadDir, isVerbose, done, mp3AddCallback)
+				//check the video before downloading it, the metadata was most likely already fetched when the video was added
+				info, err := getVideoInfo(job.url)
 				if err != nil {
-					log.Fatalln(err)
+					logging.Warn("Could not fetch the video metadata", "url", job.url, "err", err)
+				} else if err = checkVideoInfo(info, job.UserIP); err != nil {
+					logging.Info("Rejected video", "url", job.url, "ip", job.UserIP, "reason", err)
+					clients.AddNotice(job.UserIP, err.Error())
+					done(job.UserIP)
+					break
+				}
+
+				err = downloadYoutubeVideoAsMP3(&job, downloadDir, isVerbose, getTrimDuration(info, job.UserIP), done, mp3AddCallback)
+				if err == errCanceled {
+					logging.Info("Canceled the download", "url", job.url)
+				} else if err != nil {
+					logging.Fatal("Download failed", "url", job.url, "err", err)
 				}
 			}
 		}
@@ -169,16 +350,27 @@ func StartDownloadWorker(downloadDir string, mp3AddCallback func(dataDir, filena
 }
 
 //downloadYoutubeVideoAsMP3 downloads a youtube video in mp3 format
-func downloadYoutubeVideoAsMP3(song *downloadEntity, downloadDir string, verbose bool, callbackDone func(userIP string), callbackMP3Add func(songDir, filename, userIP string, newSong bool) error) error {
+//when trim is not 0, then the song is cut at the given duration
+//a canceled download returns errCanceled and stays in the download queue
+func downloadYoutubeVideoAsMP3(song *downloadEntity, downloadDir string, verbose bool, trim time.Duration, callbackDone func(userIP string), callbackMP3Add func(songDir, filename, userIP string, newSong bool) error) (err error) {
 	if len(youtubeDlDir) == 0 {
 		panic("youtube-dl directory variable was not set previously!")
 	}
 
-	defer callbackDone(song.UserIP)
+	defer func() {
+		if err != errCanceled {
+			callbackDone(song.UserIP)
+		}
+	}()
 
 	//weird that the output format get strangely parsed... "-osongs/"" should be "-o songs/""
 	//audio quality 0=best, 9=worst, default=5
-	cmd := exec.Command(youtubeDlDir, "-i", "--flat-playlist", "--no-playlist", "--extract-audio", "--audio-quality=7", "--youtube-skip-dash-manifest", "--audio-format=mp3", "-o"+downloadDir+"/%(title)s#____#%(id)s.%(ext)s", song.url)
+	args := []string{"-i", "--flat-playlist", "--no-playlist", "--extract-audio", "--audio-quality=7", "--youtube-skip-dash-manifest", "--audio-format=mp3", "-o" + downloadDir + "/%(title)s#____#%(id)s.%(ext)s"}
+	if trim > 0 {
+		//the arguments are passed to ffmpeg when converting the video to mp3
+		args = append(args, "--postprocessor-args", fmt.Sprintf("-t %d", int(trim.Seconds())))
+	}
+	cmd := exec.Command(youtubeDlDir, append(args, song.url)...)
 	var stderr bytes.Buffer
 
 	if verbose {
@@ -186,7 +378,27 @@ func downloadYoutubeVideoAsMP3(song *downloadEntity, downloadDir string, verbose
 	}
 
 	cmd.Stderr = &stderr
-	if err := cmd.Run(); err != nil {
+	prepareCommand(cmd)
+
+	workerMutex.Lock()
+	err = cmd.Start()
+	if err == nil {
+		runningCmd = cmd
+	}
+	workerMutex.Unlock()
+	if err == nil {
+		err = cmd.Wait()
+	}
+
+	workerMutex.Lock()
+	runningCmd = nil
+	canceled := stopping
+	workerMutex.Unlock()
+
+	if err != nil && canceled {
+		removePartialFiles(downloadDir, song.url)
+		return errCanceled
+	} else if err != nil {
 		errStr := string(stderr.Bytes())
 		return fmt.Errorf("%s", errStr)
 	}
@@ -198,8 +410,10 @@ func downloadYoutubeVideoAsMP3(song *downloadEntity, downloadDir string, verbose
 	}
 
 	if len(filename) > 0 {
+		stats.AddDownload(song.UserIP, false)
+
 		// we add a newly downloaded song here
-		err = callbackMP3Add(downloadDir, filename, song.UserIP, true)
+		err = passDownloadedSong(song, downloadDir, filename, true, callbackMP3Add)
 		if err != nil {
 			return fmt.Errorf("callbackMP3Add: %s", err)
 		}
@@ -208,6 +422,30 @@ func downloadYoutubeVideoAsMP3(song *downloadEntity, downloadDir string, verbose
 	return nil
 }
 
+//removePartialFiles removes the files of a canceled download from the download directory
+func removePartialFiles(downloadDir string, youtubeURL string) {
+	videoID, err := mp3.GetYoutubeVideoID(youtubeURL)
+	if err != nil {
+		logging.Error("removePartialFiles: could not get the video id", "url", youtubeURL, "err", err)
+		return
+	}
+
+	files, err := ioutil.ReadDir(downloadDir)
+	if err != nil {
+		logging.Error("removePartialFiles: could not read the download folder", "err", err)
+		return
+	}
+	for _, f := range files {
+		//the files are named like the output template of youtube-dl, f.e. title#____#id.webm.part
+		if strings.Contains(f.Name(), "#____#"+videoID) {
+			err = os.Remove(filepath.Join(downloadDir, f.Name()))
+			if err != nil {
+				logging.Error("removePartialFiles: could not remove the partial file", "err", err)
+			}
+		}
+	}
+}
+
 //checkFileExist takes a youtube url and looks for a file with the youtube video ID, if it exists, the filename is returned
 func checkFileExist(dowloadDir, youtubeURL string) (string, error) {
 	//
@@ -220,7 +458,7 @@ func checkFileExist(dowloadDir, youtubeURL string) (string, error) {
 		//does not exist, create directory
 		err = os.MkdirAll(dowloadDir, os.ModePerm)
 		if err == nil {
-			log.Println("Successfully created download dir")
+			logging.Info("Created the download folder", "path", dowloadDir)
 		}
 	}
 
@@ -235,7 +473,7 @@ func checkFileExist(dowloadDir, youtubeURL string) (string, error) {
 	isMatch, err := regexp.MatchString("https{0,1}://youtu\\.be/\\S*", youtubeURL)
 
 	if err != nil {
-		log.Fatalln("Regex for checking url against http://youtu.be/ link is invalid!")
+		logging.Fatal("Regex for checking url against http://youtu.be/ link is invalid", "err", err)
 	}
 
 	if isMatch {
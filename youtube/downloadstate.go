@@ -0,0 +1,87 @@
+package youtube
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/procrastimax/goparty/logging"
+)
+
+/**
+	The download queue is saved into a json file when the program shuts down and restored on the next start.
+	A canceled download starts again from the beginning.
+**/
+
+//savedDownload is a video of the download queue as it is saved in the downloads file
+type savedDownload struct {
+	URL    string `json:"url"`
+	UserIP string `json:"userIP"`
+}
+
+//SaveDownloads writes all pending videos of the download queue into the given json file, an empty queue removes the file
+func SaveDownloads(path string) error {
+	queue.Lock()
+	downloads := make([]savedDownload, 0, len(queue.songs))
+	for _, song := range queue.songs {
+		//the download of a kicked user is not started again
+		if song.canceled {
+			continue
+		}
+		downloads = append(downloads, savedDownload{song.url, song.UserIP})
+	}
+	queue.Unlock()
+
+	if len(downloads) == 0 {
+		err := os.Remove(path)
+		if err != nil && os.IsNotExist(err) == false {
+			return fmt.Errorf("SaveDownloads: %s", err)
+		}
+		return nil
+	}
+
+	file, err := json.MarshalIndent(downloads, "", " ")
+	if err != nil {
+		return fmt.Errorf("SaveDownloads: %s", err)
+	}
+	err = ioutil.WriteFile(path, file, 0644)
+	if err != nil {
+		return fmt.Errorf("SaveDownloads: %s", err)
+	}
+	return nil
+}
+
+//RestoreDownloads adds the videos saved in the given json file to the download queue and removes the file
+//returns the count of restored videos, a missing file restores nothing
+func RestoreDownloads(path string) (int, error) {
+	file, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("RestoreDownloads: %s", err)
+	}
+
+	var downloads []savedDownload
+	err = json.Unmarshal(file, &downloads)
+	if err != nil {
+		return 0, fmt.Errorf("RestoreDownloads: %s", err)
+	}
+
+	restored := 0
+	for _, download := range downloads {
+		//the blocklist could have changed since the video was added
+		err = Add(download.URL, download.UserIP)
+		if err != nil {
+			logging.Warn("Not restoring the download", "url", download.URL, "err", err)
+			continue
+		}
+		restored++
+	}
+
+	err = os.Remove(path)
+	if err != nil {
+		return restored, fmt.Errorf("RestoreDownloads: %s", err)
+	}
+	return restored, nil
+}
@@ -0,0 +1,240 @@
+package youtube
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/procrastimax/goparty/blocklist"
+	"github.com/procrastimax/goparty/clients"
+	"github.com/procrastimax/goparty/logging"
+)
+
+/**
+	The metadata of a video is fetched as soon as the video is added, so the video can be rejected before it gets downloaded
+	and the title of the video can already be shown while the video waits for being downloaded.
+**/
+
+const (
+	//adultAgeLimit is the age limit of age-restricted youtube videos
+	adultAgeLimit = 18
+)
+
+var (
+	videoInfos = make(map[string]*videoInfo)
+	//infoFetches are the running fetches by the url of the video, so a video is never fetched twice at the same time
+	infoFetches = make(map[string]*infoFetch)
+	infoMutex   sync.Mutex
+	limits     Limits
+	isLimited  = func(userIP string) bool { return true }
+)
+
+//Limits are the limits for downloaded videos
+type Limits struct {
+	//MaxDuration is the maximum duration of a video, 0 disables the limit
+	MaxDuration time.Duration
+	//TrimLongVideos when set, then videos longer than MaxDuration are cut at MaxDuration instead of being rejected
+	TrimLongVideos bool
+	//AllowAgeRestricted when set, then age-restricted videos can be downloaded
+	AllowAgeRestricted bool
+}
+
+//PendingDownload is a video which is downloading or waiting to be downloaded
+type PendingDownload struct {
+	URL      string
+	UserIP   string
+	UserName string
+	//Title, Channel and Duration are empty until the metadata of the video was fetched
+	Title    string
+	Channel  string
+	Duration time.Duration
+	//Downloading is true for the video which is currently downloaded
+	Downloading bool
+}
+
+//videoInfo is the metadata of a youtube video, it is fetched with youtube-dl before the video gets downloaded
+type videoInfo struct {
+	ID        string  `json:"id"`
+	Title     string  `json:"title"`
+	Channel   string  `json:"channel"`
+	ChannelID string  `json:"channel_id"`
+	Uploader  string  `json:"uploader"`
+	Duration  float64 `json:"duration"`
+	AgeLimit  int     `json:"age_limit"`
+}
+
+//infoFetch is a running fetch of video metadata, done is closed when the fetch finished
+type infoFetch struct {
+	done chan struct{}
+	info *videoInfo
+	err  error
+}
+
+//channelName returns the name of the channel, older videos only have an uploader
+func (i *videoInfo) channelName() string {
+	if len(i.Channel) > 0 {
+		return i.Channel
+	}
+	return i.Uploader
+}
+
+//duration returns the duration of the video
+func (i *videoInfo) duration() time.Duration {
+	return time.Duration(i.Duration * float64(time.Second))
+}
+
+//SetLimits sets the limits for downloaded videos, limited decides for which users the limits apply
+func SetLimits(videoLimits Limits, limited func(userIP string) bool) {
+	infoMutex.Lock()
+	limits = videoLimits
+	isLimited = limited
+	infoMutex.Unlock()
+}
+
+//fetchVideoInfo fetches the metadata of a youtube video without downloading it
+func fetchVideoInfo(url string) (*videoInfo, error) {
+	if len(youtubeDlDir) == 0 {
+		return nil, fmt.Errorf("fetchVideoInfo: youtube-dl directory variable was not set previously")
+	}
+
+	cmd := exec.Command(youtubeDlDir, "--dump-json", "--no-playlist", "--skip-download", url)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("fetchVideoInfo: %s", stderr.String())
+	}
+
+	info := &videoInfo{}
+	err := json.Unmarshal(stdout.Bytes(), info)
+	if err != nil {
+		return nil, fmt.Errorf("fetchVideoInfo: %s", err)
+	}
+	return info, nil
+}
+
+//getVideoInfo returns the metadata of a video, the metadata is only fetched when it was not fetched before
+//concurrent calls for the same video wait for the running fetch instead of fetching it again
+func getVideoInfo(url string) (*videoInfo, error) {
+	infoMutex.Lock()
+	if info, ok := videoInfos[url]; ok {
+		infoMutex.Unlock()
+		return info, nil
+	}
+	if fetch, ok := infoFetches[url]; ok {
+		infoMutex.Unlock()
+		<-fetch.done
+		return fetch.info, fetch.err
+	}
+	fetch := &infoFetch{done: make(chan struct{})}
+	infoFetches[url] = fetch
+	infoMutex.Unlock()
+
+	fetch.info, fetch.err = fetchVideoInfo(url)
+
+	infoMutex.Lock()
+	delete(infoFetches, url)
+	if fetch.err == nil {
+		videoInfos[url] = fetch.info
+	}
+	infoMutex.Unlock()
+	close(fetch.done)
+	return fetch.info, fetch.err
+}
+
+//forgetVideoInfo removes the metadata of a video which is not pending anymore
+func forgetVideoInfo(url string) {
+	infoMutex.Lock()
+	delete(videoInfos, url)
+	infoMutex.Unlock()
+}
+
+//prefetchVideoInfo fetches the metadata of a newly added video
+//videos which are rejected are removed from the download queue before they get downloaded
+func prefetchVideoInfo(url string, userIP string) {
+	info, err := getVideoInfo(url)
+	if err != nil {
+		logging.Warn("Could not fetch the video metadata", "url", url, "err", err)
+		return
+	}
+
+	err = checkVideoInfo(info, userIP)
+	if err != nil && remove(url, userIP) {
+		logging.Info("Rejected video", "url", url, "ip", userIP, "reason", err)
+		clients.AddNotice(userIP, err.Error())
+		forgetVideoInfo(url)
+	}
+}
+
+//checkVideoInfo returns an error when the video is blocked, too long or age-restricted
+func checkVideoInfo(info *videoInfo, userIP string) error {
+	err := blocklist.CheckVideo(info.ID)
+	if err != nil {
+		return err
+	}
+	err = blocklist.CheckChannel(info.ChannelID, info.channelName())
+	if err != nil {
+		return err
+	}
+	err = blocklist.CheckTitle(info.Title)
+	if err != nil {
+		return err
+	}
+
+	infoMutex.Lock()
+	videoLimits, limited := limits, isLimited(userIP)
+	infoMutex.Unlock()
+	if limited == false {
+		return nil
+	}
+
+	if info.AgeLimit >= adultAgeLimit && videoLimits.AllowAgeRestricted == false {
+		return fmt.Errorf("The video %s is age-restricted and cannot be played!", info.Title)
+	}
+
+	if videoLimits.MaxDuration > 0 && info.duration() > videoLimits.MaxDuration && videoLimits.TrimLongVideos == false {
+		return fmt.Errorf("The video %s is too long (%s), songs can be at most %s long!", info.Title, info.duration(), videoLimits.MaxDuration)
+	}
+	return nil
+}
+
+//getTrimDuration returns the duration at which the video is cut, 0 when the video is not cut
+func getTrimDuration(info *videoInfo, userIP string) time.Duration {
+	infoMutex.Lock()
+	defer infoMutex.Unlock()
+	if info == nil || limits.TrimLongVideos == false || limits.MaxDuration <= 0 || isLimited(userIP) == false {
+		return 0
+	}
+	if info.duration() > limits.MaxDuration {
+		return limits.MaxDuration
+	}
+	return 0
+}
+
+//GetPendingDownloads returns all videos which are downloading or waiting to be downloaded, the downloading video comes first
+func GetPendingDownloads() []PendingDownload {
+	queue.Lock()
+	defer queue.Unlock()
+	infoMutex.Lock()
+	defer infoMutex.Unlock()
+
+	pending := make([]PendingDownload, 0, len(queue.songs))
+	for i, song := range queue.songs {
+		download := PendingDownload{
+			URL:         song.url,
+			UserIP:      song.UserIP,
+			UserName:    clients.GetUserNameToIP(song.UserIP),
+			Downloading: i == 0,
+		}
+		if info, ok := videoInfos[song.url]; ok {
+			download.Title = info.Title
+			download.Channel = info.channelName()
+			download.Duration = info.duration()
+		}
+		pending = append(pending, download)
+	}
+	return pending
+}
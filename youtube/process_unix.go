@@ -0,0 +1,18 @@
+//go:build !windows
+
+package youtube
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+//prepareCommand starts youtube-dl in its own process group, so the ffmpeg processes it starts can be killed together with it
+func prepareCommand(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+//killCommand kills youtube-dl and all processes it started
+func killCommand(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
@@ -0,0 +1,15 @@
+//go:build windows
+
+package youtube
+
+import (
+	"os/exec"
+)
+
+//prepareCommand does nothing on windows, there are no process groups
+func prepareCommand(cmd *exec.Cmd) {}
+
+//killCommand kills youtube-dl, the processes it started keep running until they are done
+func killCommand(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}